@@ -0,0 +1,208 @@
+package check
+
+import (
+	"fmt"
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of Diagnostic.
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+)
+
+var severities = map[Severity]string{
+	ERROR:   "error",
+	WARNING: "warning",
+}
+
+func (s Severity) String() string {
+	return severities[s]
+}
+
+// Diagnostic is the semantic error or warning at Pos.
+type Diagnostic struct {
+	Pos      token.Pos
+	Severity Severity
+	Msg      string
+}
+
+// String returns "line:column: msg" for ERROR and "line:column: warning: msg" for WARNING.
+func (d Diagnostic) String() string {
+	if d.Severity == ERROR {
+		return d.Pos.String() + ": " + d.Msg
+	}
+	return d.Pos.String() + ": " + d.Severity.String() + ": " + d.Msg
+}
+
+// Diagnostics is the list of Diagnostic in the order of the source.
+type Diagnostics []Diagnostic
+
+func (l Diagnostics) Error() string {
+	switch len(l) {
+	case 0:
+		return "no diagnostics"
+	case 1:
+		return l[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more diagnostics)", l[0], len(l)-1)
+}
+
+// Err returns l itself if l has ERROR, nil otherwise.
+func (l Diagnostics) Err() error {
+	for _, d := range l {
+		if d.Severity == ERROR {
+			return l
+		}
+	}
+	return nil
+}
+
+// checker collects Diagnostics of a class.
+type checker struct {
+	st    *symboltable.SymbolTable
+	diags Diagnostics
+}
+
+// Check reports the undeclared variables, the duplicate declarations
+// and the class name which differs from filename.
+// filename is not checked if it is empty.
+func Check(filename string, cl *element.Class) Diagnostics {
+	c := &checker{st: symboltable.NewClass(cl)}
+	if filename != "" {
+		base := filepath.Base(filename)
+		if strings.TrimSuffix(base, filepath.Ext(base)) != string(cl.Cn) {
+			c.errorf(cl.CnPos, "class name '%s' differs from file name '%s'", string(cl.Cn), base)
+		}
+	}
+
+	vars := map[string]token.Pos{}
+	for _, cvd := range cl.Cvds {
+		c.declare(vars, string(cvd.Vn), cvd.VnPos)
+		for _, v := range cvd.Vns {
+			c.declare(vars, string(v.Vn), v.VnPos)
+		}
+	}
+
+	subs := map[string]token.Pos{}
+	for _, sd := range cl.Sds {
+		if prev, ok := subs[string(sd.Sn)]; ok {
+			c.errorf(sd.SnPos, "subroutine '%s' redeclared (previous declaration at %v)", string(sd.Sn), prev)
+		} else {
+			subs[string(sd.Sn)] = sd.SnPos
+		}
+		c.checkSubroutineDec(sd)
+	}
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		return c.diags[i].Pos.Offset < c.diags[j].Pos.Offset
+	})
+	return c.diags
+}
+
+// checkSubroutineDec checks the parameters, the locals and the statements of sd.
+func (c *checker) checkSubroutineDec(sd *element.SubroutineDec) {
+	c.st.StartSubroutineDec(sd)
+	vars := map[string]token.Pos{}
+	if sd.Pl != nil {
+		c.declare(vars, string(sd.Pl.Vn), sd.Pl.VnPos)
+		for _, v := range sd.Pl.Next {
+			c.declare(vars, string(v.Vn), v.VnPos)
+		}
+	}
+	for _, vd := range sd.Sb.Vd {
+		c.declare(vars, string(vd.Vn), vd.VnPos)
+		for _, v := range vd.Vns {
+			c.declare(vars, string(v.Vn), v.VnPos)
+		}
+	}
+	c.checkStatements(sd.Sb.Stmts)
+}
+
+// declare reports name if it is already in scope, otherwise adds it to scope.
+func (c *checker) declare(scope map[string]token.Pos, name string, pos token.Pos) {
+	if prev, ok := scope[name]; ok {
+		c.errorf(pos, "'%s' redeclared (previous declaration at %v)", name, prev)
+		return
+	}
+	scope[name] = pos
+}
+
+func (c *checker) checkStatements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			c.use(string(s.Vn), s.VnPos)
+			if s.Lexp != nil {
+				c.checkExpression(s.Lexp)
+			}
+			c.checkExpression(&s.Rexp)
+		case *element.IfStatement:
+			c.checkExpression(&s.LExp)
+			c.checkStatements(s.Stmts)
+			c.checkStatements(s.EStmts)
+		case *element.WhileStatement:
+			c.checkExpression(&s.Exp)
+			c.checkStatements(s.Stmts)
+		case *element.DoStatement:
+			c.checkSubroutineCall(s.Sub)
+		case *element.ReturnStatement:
+			if s.Exp != nil {
+				c.checkExpression(s.Exp)
+			}
+		}
+	}
+}
+
+func (c *checker) checkExpression(exp *element.Expression) {
+	c.checkTerm(exp.Term)
+	for _, v := range exp.Next {
+		c.checkTerm(v.Term)
+	}
+}
+
+func (c *checker) checkTerm(term element.Term) {
+	switch t := term.(type) {
+	case *element.VarName:
+		c.use(string(t.V), t.Pos)
+	case *element.CallIndex:
+		c.use(string(t.Vn), t.VnPos)
+		c.checkExpression(&t.Exp)
+	case *element.SubroutineCall:
+		c.checkSubroutineCall(t)
+	case *element.Args:
+		c.checkExpression(&t.Exp)
+	case *element.UopTerm:
+		c.checkTerm(t.Term)
+	}
+}
+
+// checkSubroutineCall checks the arguments of sbc.
+// The name before '.' is either varName or className, so it is not reported.
+func (c *checker) checkSubroutineCall(sbc *element.SubroutineCall) {
+	for i := range sbc.ExpL {
+		c.checkExpression(&sbc.ExpL[i])
+	}
+}
+
+// use reports name if it is declared in neither the class nor the subroutine scope.
+func (c *checker) use(name string, pos token.Pos) {
+	if _, ok := c.st.Lookup(name); !ok {
+		c.errorf(pos, "undeclared variable '%s'", name)
+	}
+}
+
+func (c *checker) errorf(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{
+		Pos:      pos,
+		Severity: ERROR,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
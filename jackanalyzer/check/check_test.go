@@ -0,0 +1,131 @@
+package check
+
+import (
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, s string) *element.Class {
+	t.Helper()
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		s        string
+		want     []string
+	}{
+		{
+			"no diagnostics",
+			"Main.jack",
+			`class Main {
+  field int x;
+  method void set(int a) {
+    var Array b;
+    let b[x] = a;
+    do b.dispose();
+    do Output.printInt(x);
+    return;
+  }
+}`,
+			nil,
+		},
+		{
+			"undeclared",
+			"",
+			`class Main {
+  function void main() {
+    let x = y[z] + -w;
+    if (a) { do Output.printInt(b); } else { return c; }
+    while (d) {}
+    return;
+  }
+}`,
+			[]string{
+				"3:9: undeclared variable 'x'",
+				"3:13: undeclared variable 'y'",
+				"3:15: undeclared variable 'z'",
+				"3:21: undeclared variable 'w'",
+				"4:9: undeclared variable 'a'",
+				"4:33: undeclared variable 'b'",
+				"4:53: undeclared variable 'c'",
+				"5:12: undeclared variable 'd'",
+			},
+		},
+		{
+			"duplicate",
+			"",
+			`class Main {
+  field int x, x;
+  static boolean x;
+  function void main(int a, int a) {
+    var int a, b;
+    var char b, x;
+    return;
+  }
+  method void main() {
+    return;
+  }
+}`,
+			[]string{
+				"2:16: 'x' redeclared (previous declaration at 2:13)",
+				"3:18: 'x' redeclared (previous declaration at 2:13)",
+				"4:33: 'a' redeclared (previous declaration at 4:26)",
+				"5:13: 'a' redeclared (previous declaration at 4:26)",
+				"6:14: 'b' redeclared (previous declaration at 5:16)",
+				"9:15: subroutine 'main' redeclared (previous declaration at 4:17)",
+			},
+		},
+		{
+			"file name",
+			"path/to/Main.jack",
+			"class Foo {}",
+			[]string{"1:7: class name 'Foo' differs from file name 'Main.jack'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.filename, parse(t, tt.s))
+			var gots []string
+			for _, d := range got {
+				gots = append(gots, d.String())
+			}
+			if strings.Join(gots, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Check() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if (got.Err() != nil) != (len(tt.want) != 0) {
+				t.Errorf("Err() = %v", got.Err())
+			}
+		})
+	}
+}
+
+func TestDiagnostics_Error(t *testing.T) {
+	l := Diagnostics{
+		{Pos: token.Pos{Line: 1, Column: 2}, Severity: ERROR, Msg: "a"},
+		{Pos: token.Pos{Line: 3, Column: 4}, Severity: WARNING, Msg: "b"},
+	}
+	if got, want := l.Error(), "1:2: a (and 1 more diagnostics)"; got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
+	}
+	if got, want := l[1].String(), "3:4: warning: b"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+	if err := l[1:].Err(); err != nil {
+		t.Errorf("Err() of warnings = %v, want nil", err)
+	}
+}
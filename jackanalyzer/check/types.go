@@ -0,0 +1,269 @@
+package check
+
+import (
+	"fmt"
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"sort"
+)
+
+// TypeConfig configures CheckTypes.
+type TypeConfig struct {
+	// Escalate reports the type mismatches as ERROR instead of WARNING.
+	Escalate bool
+}
+
+// The type names inferred by typeChecker.
+// The empty string is the unknown type which matches every type.
+const (
+	unknownType = ""
+	intType     = "int"
+	charType    = "char"
+	booleanType = "boolean"
+	voidType    = "void"
+	nullType    = "null"
+	arrayType   = "Array"
+	stringType  = "String"
+)
+
+// typeChecker infers the types of the terms in a class.
+type typeChecker struct {
+	conf  TypeConfig
+	cl    *element.Class
+	st    *symboltable.SymbolTable
+	sd    *element.SubroutineDec // current subroutine
+	diags Diagnostics
+}
+
+// CheckTypes reports the type mismatches in the let and return statements and the expressions:
+// assigning or returning a value of the other type, returning a value from a void subroutine,
+// 'return;' in a non-void subroutine, calling a method on int, char or boolean,
+// indexing a non-Array variable and the operands of the arithmetic and comparison operators.
+//
+// Jack is weakly typed, so int and char are interchangeable, null and Array match every class,
+// and the term whose type cannot be inferred is not reported.
+func CheckTypes(cl *element.Class, conf TypeConfig) Diagnostics {
+	tc := &typeChecker{
+		conf: conf,
+		cl:   cl,
+		st:   symboltable.NewClass(cl),
+	}
+	for _, sd := range cl.Sds {
+		tc.sd = sd
+		tc.st.StartSubroutineDec(sd)
+		tc.checkStatements(sd.Sb.Stmts)
+	}
+	sort.SliceStable(tc.diags, func(i, j int) bool {
+		return tc.diags[i].Pos.Offset < tc.diags[j].Pos.Offset
+	})
+	return tc.diags
+}
+
+func (tc *typeChecker) checkStatements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			tc.checkLet(s)
+		case *element.IfStatement:
+			tc.typeOf(&s.LExp)
+			tc.checkStatements(s.Stmts)
+			tc.checkStatements(s.EStmts)
+		case *element.WhileStatement:
+			tc.typeOf(&s.Exp)
+			tc.checkStatements(s.Stmts)
+		case *element.DoStatement:
+			tc.typeOfSubroutineCall(s.Sub)
+		case *element.ReturnStatement:
+			tc.checkReturn(s)
+		}
+	}
+}
+
+// checkLet checks the assignment to the variable or the index of the array.
+func (tc *typeChecker) checkLet(ls *element.LetStatement) {
+	s, ok := tc.st.Lookup(string(ls.Vn))
+	rt := tc.typeOf(&ls.Rexp)
+	if ls.Lexp != nil {
+		if ok {
+			tc.checkIndex(s, ls.VnPos)
+		}
+		tc.checkOperand(ls.Lexp, ls.VnPos, "index")
+		return
+	}
+	if ok && !assignable(s.Type, rt) {
+		tc.reportf(ls.VnPos, "cannot assign %s to %s variable '%s'", rt, s.Type, s.Name)
+	}
+}
+
+// checkReturn checks the returned value against the return type of the subroutine.
+func (tc *typeChecker) checkReturn(rs *element.ReturnStatement) {
+	want := element.TypeName(tc.sd.St)
+	if rs.Exp == nil {
+		if want != voidType {
+			tc.reportf(rs.Pos, "missing return value in subroutine '%s' returning %s", string(tc.sd.Sn), want)
+		}
+		return
+	}
+	got := tc.typeOf(rs.Exp)
+	switch {
+	case want == voidType:
+		tc.reportf(rs.Pos, "void subroutine '%s' returns a value", string(tc.sd.Sn))
+	case !assignable(want, got):
+		tc.reportf(rs.Pos, "cannot return %s from subroutine '%s' returning %s", got, string(tc.sd.Sn), want)
+	}
+}
+
+// typeOf returns the type of the expression.
+//
+//  '+' '-' '*' '/' -> int
+//  '<' '>' '='     -> boolean
+//  '&' '|'         -> the type of the operands
+func (tc *typeChecker) typeOf(exp *element.Expression) string {
+	t := tc.typeOfTerm(exp.Term)
+	for _, v := range exp.Next {
+		rt := tc.typeOfTerm(v.Term)
+		switch op := string(v.Bop); op {
+		case "+", "-", "*", "/":
+			tc.checkArithmetic(op, t, rt, v.BopPos)
+			t = intType
+		case "<", ">":
+			tc.checkArithmetic(op, t, rt, v.BopPos)
+			t = booleanType
+		case "=":
+			t = booleanType
+		case "&", "|":
+			if t != rt || (t != booleanType && !isInt(t)) {
+				t = unknownType
+			}
+		}
+	}
+	return t
+}
+
+func (tc *typeChecker) typeOfTerm(term element.Term) string {
+	switch t := term.(type) {
+	case *element.IntegerConstant:
+		return intType
+	case *element.StringConstant:
+		return stringType
+	case *element.KeywordConstant:
+		switch t.V {
+		case "true", "false":
+			return booleanType
+		case "null":
+			return nullType
+		case "this":
+			return string(tc.cl.Cn)
+		}
+	case *element.VarName:
+		if s, ok := tc.st.Lookup(string(t.V)); ok {
+			return s.Type
+		}
+	case *element.CallIndex:
+		if s, ok := tc.st.Lookup(string(t.Vn)); ok {
+			tc.checkIndex(s, t.VnPos)
+		}
+		tc.checkOperand(&t.Exp, t.VnPos, "index")
+		return unknownType
+	case *element.SubroutineCall:
+		return tc.typeOfSubroutineCall(t)
+	case *element.Args:
+		return tc.typeOf(&t.Exp)
+	case *element.UopTerm:
+		ot := tc.typeOfTerm(t.Term)
+		if t.Uop == "-" {
+			if ot != unknownType && !isInt(ot) {
+				tc.reportf(t.UopPos, "operator '-' on %s", ot)
+			}
+			return intType
+		}
+		return ot
+	}
+	return unknownType
+}
+
+// typeOfSubroutineCall returns the return type of the subroutine of this class.
+// The subroutine of the other class is unknown.
+func (tc *typeChecker) typeOfSubroutineCall(sbc *element.SubroutineCall) string {
+	for i := range sbc.ExpL {
+		tc.typeOf(&sbc.ExpL[i])
+	}
+	if sbc.Dot != "" {
+		if s, ok := tc.st.Lookup(string(sbc.Name)); ok {
+			if isPrimitive(s.Type) {
+				tc.reportf(sbc.Pos, "cannot call method '%s' on '%s' of type %s", string(sbc.Sn), s.Name, s.Type)
+				return unknownType
+			}
+			if s.Type != string(tc.cl.Cn) {
+				return unknownType
+			}
+		} else if sbc.Name != tc.cl.Cn {
+			return unknownType
+		}
+	}
+	for _, sd := range tc.cl.Sds {
+		if sd.Sn == sbc.Sn {
+			return element.TypeName(sd.St)
+		}
+	}
+	return unknownType
+}
+
+// checkIndex reports s if it is not Array.
+func (tc *typeChecker) checkIndex(s *symboltable.Symbol, pos token.Pos) {
+	if s.Type != arrayType {
+		tc.reportf(pos, "cannot index non-Array variable '%s' of type %s", s.Name, s.Type)
+	}
+}
+
+// checkOperand reports exp if its type is not int or char.
+func (tc *typeChecker) checkOperand(exp *element.Expression, pos token.Pos, what string) {
+	if t := tc.typeOf(exp); t != unknownType && !isInt(t) {
+		tc.reportf(pos, "%s of type %s, want int", what, t)
+	}
+}
+
+// checkArithmetic reports the operands of op which are not int or char.
+func (tc *typeChecker) checkArithmetic(op, lt, rt string, pos token.Pos) {
+	for _, t := range []string{lt, rt} {
+		if t != unknownType && !isInt(t) {
+			tc.reportf(pos, "operator '%s' on %s", op, t)
+			return
+		}
+	}
+}
+
+func (tc *typeChecker) reportf(pos token.Pos, format string, args ...interface{}) {
+	sev := WARNING
+	if tc.conf.Escalate {
+		sev = ERROR
+	}
+	tc.diags = append(tc.diags, Diagnostic{
+		Pos:      pos,
+		Severity: sev,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// assignable reports whether the value of src can be assigned to dst.
+func assignable(dst, src string) bool {
+	switch {
+	case dst == unknownType || src == unknownType || dst == src:
+		return true
+	case isInt(dst) && isInt(src):
+		return true
+	case isPrimitive(dst) || isPrimitive(src):
+		return false
+	}
+	// dst and src are classes or null.
+	return src == nullType || dst == arrayType || src == arrayType
+}
+
+func isInt(t string) bool {
+	return t == intType || t == charType
+}
+
+func isPrimitive(t string) bool {
+	return t == intType || t == charType || t == booleanType
+}
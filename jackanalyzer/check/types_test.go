@@ -0,0 +1,104 @@
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			"well typed",
+			`class Point {
+  field int x;
+  field Array a;
+  field Point next;
+  constructor Point new(char c) {
+    let x = c + 1;
+    let a[x] = true;
+    let next = null;
+    let a = next;
+    if ((x < 1) & ~(x = 2)) { let x = -x; }
+    return this;
+  }
+  method int get() {
+    let x = get() * Math.abs(x);
+    return a[0];
+  }
+  function void main() {
+    do Output.printString("s");
+    return;
+  }
+}`,
+			nil,
+		},
+		{
+			"mismatches",
+			`class Main {
+  field int x;
+  field boolean b;
+  field String s;
+  method int f() {
+    let x = b;
+    let b = 1 + true;
+    let s = 1;
+    let x[0] = 1;
+    let x = s[b];
+    do x.foo();
+    let x = -b;
+    return;
+  }
+  method void g() {
+    return 1;
+  }
+  method boolean h() {
+    return f();
+  }
+}`,
+			[]string{
+				"6:9: warning: cannot assign boolean to int variable 'x'",
+				"7:9: warning: cannot assign int to boolean variable 'b'",
+				"7:15: warning: operator '+' on boolean",
+				"8:9: warning: cannot assign int to String variable 's'",
+				"9:9: warning: cannot index non-Array variable 'x' of type int",
+				"10:13: warning: cannot index non-Array variable 's' of type String",
+				"10:13: warning: index of type boolean, want int",
+				"11:8: warning: cannot call method 'foo' on 'x' of type int",
+				"12:13: warning: operator '-' on boolean",
+				"13:5: warning: missing return value in subroutine 'f' returning int",
+				"16:5: warning: void subroutine 'g' returns a value",
+				"19:5: warning: cannot return int from subroutine 'h' returning boolean",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckTypes(parse(t, tt.s), TypeConfig{})
+			var gots []string
+			for _, d := range got {
+				gots = append(gots, d.String())
+			}
+			if strings.Join(gots, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("CheckTypes() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if err := got.Err(); err != nil {
+				t.Errorf("Err() = %v, want nil for warnings", err)
+			}
+		})
+	}
+}
+
+func TestCheckTypes_escalate(t *testing.T) {
+	s := "class Main { function void main() { var int x; let x = true; return; } }"
+	got := CheckTypes(parse(t, s), TypeConfig{Escalate: true})
+	if len(got) != 1 || got[0].Severity != ERROR {
+		t.Fatalf("CheckTypes() = %v", got)
+	}
+	if got.Err() == nil {
+		t.Errorf("Err() = nil, want error")
+	}
+}
@@ -13,6 +13,9 @@ type CompilationEngine struct {
 	e *xml.Encoder
 }
 
+// New returns CompilationEngine.
+//
+// t is the head of the token list returned by Tokenizer.Tokenize.
 func New(t token.Token, e *xml.Encoder) *CompilationEngine {
 	ce := &CompilationEngine{
 		t: t,
@@ -21,29 +24,334 @@ func New(t token.Token, e *xml.Encoder) *CompilationEngine {
 	return ce
 }
 
-func (ce *CompilationEngine) Compile() {}
+// Compile writes the parse tree of the class to the encoder.
+func (ce *CompilationEngine) Compile() error {
+	ce.advance()
+	if err := ce.compileClass(); err != nil {
+		return err
+	}
+	if ce.t.TokenType != 0 {
+		return xerrors.Errorf("unexpected %s after class", describe(ce.t))
+	}
+	return nil
+}
 
-func (ce *CompilationEngine) compileClass() {}
+// Compile Class.
+//
+//  'class' className '{' classVarDec* subroutineDec* '}'
+func (ce *CompilationEngine) compileClass() error {
+	start := xml.StartElement{Name: xml.Name{Local: "class"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.CLASS); err != nil {
+		return err
+	}
+	if err := ce.writeIdentifier(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol("{"); err != nil {
+		return err
+	}
+	for ce.isKeyword(token.STATIC, token.FIELD) {
+		if err := ce.compileClassVarDec(); err != nil {
+			return err
+		}
+	}
+	for ce.isKeyword(token.CONSTRUCTOR, token.FUNCTION, token.METHOD) {
+		if err := ce.compileSubroutine(); err != nil {
+			return err
+		}
+	}
+	if err := ce.writeSymbol("}"); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileClassVarDec() {}
+// Compile ClassVarDec.
+//
+//  ( 'static' | 'field' ) type varName (',' varName)* ';'
+func (ce *CompilationEngine) compileClassVarDec() error {
+	start := xml.StartElement{Name: xml.Name{Local: "classVarDec"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.STATIC, token.FIELD); err != nil {
+		return err
+	}
+	if err := ce.writeVarNames(); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileSubroutine() {}
+// Compile SubroutineDec.
+//
+//  ( 'constructor' | 'function' | 'method' )
+//  ( 'void' | type ) subroutineName '(' parameterList ')'
+//  subroutineBody
+func (ce *CompilationEngine) compileSubroutine() error {
+	start := xml.StartElement{Name: xml.Name{Local: "subroutineDec"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.CONSTRUCTOR, token.FUNCTION, token.METHOD); err != nil {
+		return err
+	}
+	if ce.isKeyword(token.VOID) {
+		ce.writeToken()
+	} else if err := ce.writeType(); err != nil {
+		return err
+	}
+	if err := ce.writeIdentifier(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol("("); err != nil {
+		return err
+	}
+	if err := ce.compileParameterList(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol(")"); err != nil {
+		return err
+	}
+	if err := ce.compileSubroutineBody(); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileParameterList() {}
+// Compile ParameterList.
+//
+//  ( type varName (',' type varName)* )?
+func (ce *CompilationEngine) compileParameterList() error {
+	start := xml.StartElement{Name: xml.Name{Local: "parameterList"}}
+	ce.e.EncodeToken(start)
+	if !ce.isSymbol(")") {
+		for {
+			if err := ce.writeType(); err != nil {
+				return err
+			}
+			if err := ce.writeIdentifier(); err != nil {
+				return err
+			}
+			if !ce.isSymbol(",") {
+				break
+			}
+			ce.writeToken()
+		}
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileVarDec() {}
+// Compile SubroutineBody.
+//
+//  '{' varDec* statements '}'
+func (ce *CompilationEngine) compileSubroutineBody() error {
+	start := xml.StartElement{Name: xml.Name{Local: "subroutineBody"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeSymbol("{"); err != nil {
+		return err
+	}
+	for ce.isKeyword(token.VAR) {
+		if err := ce.compileVarDec(); err != nil {
+			return err
+		}
+	}
+	if err := ce.compileStatements(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol("}"); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
+
+// Compile VarDec.
+//
+//  'var' type varName (',' varName)* ';'
+func (ce *CompilationEngine) compileVarDec() error {
+	start := xml.StartElement{Name: xml.Name{Local: "varDec"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.VAR); err != nil {
+		return err
+	}
+	if err := ce.writeVarNames(); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
+
+// Compile Statements.
+//
+//  statement*
+func (ce *CompilationEngine) compileStatements() error {
+	start := xml.StartElement{Name: xml.Name{Local: "statements"}}
+	ce.e.EncodeToken(start)
+	for {
+		var err error
+		switch {
+		case ce.isKeyword(token.LET):
+			err = ce.compileLet()
+		case ce.isKeyword(token.IF):
+			err = ce.compileIf()
+		case ce.isKeyword(token.WHILE):
+			err = ce.compileWhile()
+		case ce.isKeyword(token.DO):
+			err = ce.compileDo()
+		case ce.isKeyword(token.RETURN):
+			err = ce.compileReturn()
+		default:
+			ce.e.EncodeToken(start.End())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
 
-func (ce *CompilationEngine) compileStatements() {}
+// Compile Do.
+//
+//  'do' subroutineCall ';'
+func (ce *CompilationEngine) compileDo() error {
+	start := xml.StartElement{Name: xml.Name{Local: "doStatement"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.DO); err != nil {
+		return err
+	}
+	if err := ce.compileSubroutineCall(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol(";"); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
+
+// Compile Let.
+//
+//  'let' varName ( '[' expression ']' )? '=' expression ';'
+func (ce *CompilationEngine) compileLet() error {
+	start := xml.StartElement{Name: xml.Name{Local: "letStatement"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.LET); err != nil {
+		return err
+	}
+	if err := ce.writeIdentifier(); err != nil {
+		return err
+	}
+	if ce.isSymbol("[") {
+		ce.writeToken()
+		if err := ce.compileExpression(); err != nil {
+			return err
+		}
+		if err := ce.writeSymbol("]"); err != nil {
+			return err
+		}
+	}
+	if err := ce.writeSymbol("="); err != nil {
+		return err
+	}
+	if err := ce.compileExpression(); err != nil {
+		return err
+	}
+	if err := ce.writeSymbol(";"); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileDo() {}
+// Compile While.
+//
+//  'while' '(' expression ')' '{' statements '}'
+func (ce *CompilationEngine) compileWhile() error {
+	start := xml.StartElement{Name: xml.Name{Local: "whileStatement"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.WHILE); err != nil {
+		return err
+	}
+	if err := ce.compileCondition(); err != nil {
+		return err
+	}
+	if err := ce.compileBlock(); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileLet() {}
+// Compile Return.
+//
+//  'return' expression? ';'
+func (ce *CompilationEngine) compileReturn() error {
+	start := xml.StartElement{Name: xml.Name{Local: "returnStatement"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.RETURN); err != nil {
+		return err
+	}
+	if !ce.isSymbol(";") {
+		if err := ce.compileExpression(); err != nil {
+			return err
+		}
+	}
+	if err := ce.writeSymbol(";"); err != nil {
+		return err
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileWhile() {}
+// Compile If.
+//
+//  'if' '(' expression ')' '{' statements '}'
+//  ( 'else' '{' statements '}' )?
+func (ce *CompilationEngine) compileIf() error {
+	start := xml.StartElement{Name: xml.Name{Local: "ifStatement"}}
+	ce.e.EncodeToken(start)
+	if err := ce.writeKeyword(token.IF); err != nil {
+		return err
+	}
+	if err := ce.compileCondition(); err != nil {
+		return err
+	}
+	if err := ce.compileBlock(); err != nil {
+		return err
+	}
+	if ce.isKeyword(token.ELSE) {
+		ce.writeToken()
+		if err := ce.compileBlock(); err != nil {
+			return err
+		}
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
-func (ce *CompilationEngine) compileReturn() {}
+// compileCondition compiles '(' expression ')' of if and while.
+func (ce *CompilationEngine) compileCondition() error {
+	if err := ce.writeSymbol("("); err != nil {
+		return err
+	}
+	if err := ce.compileExpression(); err != nil {
+		return err
+	}
+	return ce.writeSymbol(")")
+}
 
-func (ce *CompilationEngine) compileIf() {}
+// compileBlock compiles '{' statements '}' of if, else and while.
+func (ce *CompilationEngine) compileBlock() error {
+	if err := ce.writeSymbol("{"); err != nil {
+		return err
+	}
+	if err := ce.compileStatements(); err != nil {
+		return err
+	}
+	return ce.writeSymbol("}")
+}
 
 // Compile Expression.
 //
@@ -51,14 +359,14 @@ func (ce *CompilationEngine) compileIf() {}
 func (ce *CompilationEngine) compileExpression() error {
 	start := xml.StartElement{Name: xml.Name{Local: "expression"}}
 	ce.e.EncodeToken(start)
-	ce.compileTerm()
+	if err := ce.compileTerm(); err != nil {
+		return err
+	}
 	for ce.t.IsOp() {
-		ce.e.EncodeElement(genElement(ce.t))
-		if !ce.t.HasMoreTokens() {
-			return xerrors.New("invalid syntax. compileExpression")
+		ce.writeToken()
+		if err := ce.compileTerm(); err != nil {
+			return err
 		}
-		ce.t.Advance()
-		ce.compileTerm()
 	}
 	ce.e.EncodeToken(start.End())
 	return nil
@@ -75,19 +383,201 @@ func (ce *CompilationEngine) compileTerm() error {
 	ce.e.EncodeToken(start)
 
 	switch ce.t.TokenType {
-	case token.INT_CONST, token.STRING_CONST, token.KEYWORD:
-		// integerConstant | stringConstant | keywordConstant
+	case token.INT_CONST, token.STRING_CONST:
+		// integerConstant | stringConstant
+		ce.writeToken()
+	case token.KEYWORD:
+		// keywordConstant
+		if err := ce.writeKeyword(token.TRUE, token.FALSE, token.NULL, token.THIS); err != nil {
+			return err
+		}
+	case token.IDENTIFIER:
+		// varName | varName '[' expression ']' | subroutineCall
+		switch nxt := ce.t.Next; {
+		case nxt != nil && nxt.TokenType == token.SYMBOL && nxt.Symbol == "[":
+			ce.writeToken()
+			ce.writeToken()
+			if err := ce.compileExpression(); err != nil {
+				return err
+			}
+			if err := ce.writeSymbol("]"); err != nil {
+				return err
+			}
+		case nxt != nil && nxt.TokenType == token.SYMBOL && (nxt.Symbol == "(" || nxt.Symbol == "."):
+			if err := ce.compileSubroutineCall(); err != nil {
+				return err
+			}
+		default:
+			ce.writeToken()
+		}
+	case token.SYMBOL:
+		switch ce.t.Symbol {
+		case "(":
+			// '(' expression ')'
+			ce.writeToken()
+			if err := ce.compileExpression(); err != nil {
+				return err
+			}
+			if err := ce.writeSymbol(")"); err != nil {
+				return err
+			}
+		case "-", "~":
+			// unaryOp term
+			ce.writeToken()
+			if err := ce.compileTerm(); err != nil {
+				return err
+			}
+		default:
+			return xerrors.Errorf("expected term, got %s", describe(ce.t))
+		}
+	default:
+		return xerrors.Errorf("expected term, got %s", describe(ce.t))
 	}
 	ce.e.EncodeToken(start.End())
 	return nil
 }
 
-func (ce *CompilationEngine) compileExpressionList() {}
+// compileSubroutineCall compiles subroutineCall.
+// It is written into the enclosing element.
+//
+//  subroutineName '(' expressionList ')' | (className | varName) '.' subroutineName '(' expressionList ')'
+func (ce *CompilationEngine) compileSubroutineCall() error {
+	if err := ce.writeIdentifier(); err != nil {
+		return err
+	}
+	if ce.isSymbol(".") {
+		ce.writeToken()
+		if err := ce.writeIdentifier(); err != nil {
+			return err
+		}
+	}
+	if err := ce.writeSymbol("("); err != nil {
+		return err
+	}
+	if err := ce.compileExpressionList(); err != nil {
+		return err
+	}
+	return ce.writeSymbol(")")
+}
+
+// Compile ExpressionList.
+//
+//  (expression (',' expression)* )?
+func (ce *CompilationEngine) compileExpressionList() error {
+	start := xml.StartElement{Name: xml.Name{Local: "expressionList"}}
+	ce.e.EncodeToken(start)
+	if !ce.isSymbol(")") {
+		for {
+			if err := ce.compileExpression(); err != nil {
+				return err
+			}
+			if !ce.isSymbol(",") {
+				break
+			}
+			ce.writeToken()
+		}
+	}
+	ce.e.EncodeToken(start.End())
+	return nil
+}
 
+// writeVarNames writes type varName (',' varName)* ';'
+func (ce *CompilationEngine) writeVarNames() error {
+	if err := ce.writeType(); err != nil {
+		return err
+	}
+	if err := ce.writeIdentifier(); err != nil {
+		return err
+	}
+	for ce.isSymbol(",") {
+		ce.writeToken()
+		if err := ce.writeIdentifier(); err != nil {
+			return err
+		}
+	}
+	return ce.writeSymbol(";")
+}
+
+// writeType writes type.
+//
+//  'int' | 'char' | 'boolean' | className
+func (ce *CompilationEngine) writeType() error {
+	if ce.t.TokenType == token.IDENTIFIER {
+		ce.writeToken()
+		return nil
+	}
+	if err := ce.writeKeyword(token.INT, token.CHAR, token.BOOLEAN); err != nil {
+		return xerrors.Errorf("expected type, got %s", describe(ce.t))
+	}
+	return nil
+}
+
+// writeKeyword writes the current token if it is one of kws.
+func (ce *CompilationEngine) writeKeyword(kws ...token.Keyword) error {
+	if !ce.isKeyword(kws...) {
+		return xerrors.Errorf("expected %v, got %s", kws, describe(ce.t))
+	}
+	ce.writeToken()
+	return nil
+}
+
+// writeSymbol writes the current token if it is the symbol s.
+func (ce *CompilationEngine) writeSymbol(s string) error {
+	if !ce.isSymbol(s) {
+		return xerrors.Errorf("expected '%s', got %s", s, describe(ce.t))
+	}
+	ce.writeToken()
+	return nil
+}
+
+// writeIdentifier writes the current token if it is an identifier.
+func (ce *CompilationEngine) writeIdentifier() error {
+	if ce.t.TokenType != token.IDENTIFIER {
+		return xerrors.Errorf("expected identifier, got %s", describe(ce.t))
+	}
+	ce.writeToken()
+	return nil
+}
+
+// writeToken writes the current token and advances.
 func (ce *CompilationEngine) writeToken() {
-	if ce.t.HasMoreTokens() {
-		ce.e.EncodeElement(genElement(ce.t))
+	ce.e.EncodeElement(genElement(ce.t))
+	ce.advance()
+}
+
+// advance makes the next token the current token.
+// The current token becomes zero value after the last token.
+func (ce *CompilationEngine) advance() {
+	if !ce.t.HasMoreTokens() {
+		ce.t = token.Token{}
+		return
+	}
+	ce.t.Advance()
+}
+
+func (ce *CompilationEngine) isKeyword(kws ...token.Keyword) bool {
+	if ce.t.TokenType != token.KEYWORD {
+		return false
+	}
+	for _, v := range kws {
+		if ce.t.Keyword == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (ce *CompilationEngine) isSymbol(s string) bool {
+	return ce.t.TokenType == token.SYMBOL && ce.t.Symbol == s
+}
+
+// describe returns the token for error messages.
+func describe(t token.Token) string {
+	if t.TokenType == 0 {
+		return "EOF"
 	}
+	c, l := genElement(t)
+	return l.Name.Local + " '" + c[1:len(c)-1] + "'"
 }
 
 // generate Element for *xml.EncodeElement.
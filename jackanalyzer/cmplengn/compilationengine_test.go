@@ -3,12 +3,39 @@ package cmplengn
 import (
 	"bytes"
 	"encoding/xml"
+	"io/ioutil"
 	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 )
 
+// initEngine returns CompilationEngine whose current token is the first token of s.
+func initEngine(s string) (*CompilationEngine, *bytes.Buffer) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		panic(err)
+	}
+	ce := New(*head, e)
+	ce.advance()
+	return ce, &b
+}
+
+// lf returns the compare file b with LF line endings.
+// The output ends the lines with LF, so it matches the compare files of
+// nand2tetris except for the line endings, not byte for byte.
+func lf(b []byte) string {
+	return strings.Replace(string(b), "\r\n", "\n", -1)
+}
+
+func trimXML(s string) string {
+	return strings.TrimRight(strings.TrimLeft(s, "\n"), "\n")
+}
+
 func TestNew(t *testing.T) {
 	type args struct {
 		t token.Token
@@ -114,44 +141,454 @@ func Test_genElement(t *testing.T) {
 	}
 }
 
+func TestCompilationEngine_Compile(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.jack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Run(f, func(t *testing.T) {
+			src, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ioutil.ReadFile(strings.TrimSuffix(f, ".jack") + ".xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			e := NewEncoder(&b)
+			head, err := tokenizer.New(bytes.NewReader(src)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := New(*head, e).Compile(); err != nil {
+				t.Fatalf("ce.Compile() error = %v", err)
+			}
+			e.Flush()
+			b.WriteString("\n")
+			if got := b.String(); got != lf(want) {
+				t.Errorf("ce.Compile() = %v", got)
+				t.Errorf("wantXml = %v", string(want))
+			}
+		})
+	}
+}
+
+func TestCompilationEngine_Compile_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		wantErr string
+	}{
+		{
+			"not class",
+			"function void main() {}",
+			"expected [class], got keyword 'function'",
+		},
+		{
+			"missing class name",
+			"class {}",
+			"expected identifier, got symbol '{'",
+		},
+		{
+			"missing closing brace",
+			"class Main {",
+			"expected '}', got EOF",
+		},
+		{
+			"token after class",
+			"class Main {} }",
+			"unexpected symbol '}' after class",
+		},
+		{
+			"invalid type",
+			"class Main { field void x; }",
+			"expected type, got keyword 'void'",
+		},
+		{
+			"missing semicolon",
+			"class Main { function void main() { return } }",
+			"expected term, got symbol '}'",
+		},
+		{
+			"missing let equal",
+			"class Main { function void main() { let x 1; return; } }",
+			"expected '=', got integerConstant '1'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			head, err := tokenizer.New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = New(*head, xml.NewEncoder(&b)).Compile()
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ce.Compile() error got = %v, want = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompilationEngine_compileClassVarDec(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"class type",
+			"field Square square, other;",
+			`
+<classVarDec>
+  <keyword> field </keyword>
+  <identifier> Square </identifier>
+  <identifier> square </identifier>
+  <symbol> , </symbol>
+  <identifier> other </identifier>
+  <symbol> ; </symbol>
+</classVarDec>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce, b := initEngine(tt.s)
+			if err := ce.compileClassVarDec(); err != nil {
+				t.Fatalf("ce.compileClassVarDec() error = %v", err)
+			}
+			ce.e.Flush()
+			if got, want := b.String(), trimXML(tt.want); got != want {
+				t.Errorf("ce.compileClassVarDec() = %v", got)
+				t.Errorf("wantXml = %v", want)
+			}
+		})
+	}
+}
+
+func TestCompilationEngine_compileParameterList(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"empty",
+			")",
+			`
+<parameterList>
+</parameterList>
+`,
+		},
+		{
+			"parameters",
+			"int Ax, Point p)",
+			`
+<parameterList>
+  <keyword> int </keyword>
+  <identifier> Ax </identifier>
+  <symbol> , </symbol>
+  <identifier> Point </identifier>
+  <identifier> p </identifier>
+</parameterList>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce, b := initEngine(tt.s)
+			if err := ce.compileParameterList(); err != nil {
+				t.Fatalf("ce.compileParameterList() error = %v", err)
+			}
+			ce.e.Flush()
+			if got, want := b.String(), trimXML(tt.want); got != want {
+				t.Errorf("ce.compileParameterList() = %v", got)
+				t.Errorf("wantXml = %v", want)
+			}
+		})
+	}
+}
+
+func TestCompilationEngine_compileStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"empty",
+			"}",
+			`
+<statements>
+</statements>
+`,
+		},
+		{
+			"let array",
+			"let a[i] = 1;",
+			`
+<statements>
+  <letStatement>
+    <keyword> let </keyword>
+    <identifier> a </identifier>
+    <symbol> [ </symbol>
+    <expression>
+      <term>
+        <identifier> i </identifier>
+      </term>
+    </expression>
+    <symbol> ] </symbol>
+    <symbol> = </symbol>
+    <expression>
+      <term>
+        <integerConstant> 1 </integerConstant>
+      </term>
+    </expression>
+    <symbol> ; </symbol>
+  </letStatement>
+</statements>
+`,
+		},
+		{
+			"if else",
+			"if (x) {} else { return; }",
+			`
+<statements>
+  <ifStatement>
+    <keyword> if </keyword>
+    <symbol> ( </symbol>
+    <expression>
+      <term>
+        <identifier> x </identifier>
+      </term>
+    </expression>
+    <symbol> ) </symbol>
+    <symbol> { </symbol>
+    <statements>
+    </statements>
+    <symbol> } </symbol>
+    <keyword> else </keyword>
+    <symbol> { </symbol>
+    <statements>
+      <returnStatement>
+        <keyword> return </keyword>
+        <symbol> ; </symbol>
+      </returnStatement>
+    </statements>
+    <symbol> } </symbol>
+  </ifStatement>
+</statements>
+`,
+		},
+		{
+			"while and do",
+			"while (true) { do draw(); }",
+			`
+<statements>
+  <whileStatement>
+    <keyword> while </keyword>
+    <symbol> ( </symbol>
+    <expression>
+      <term>
+        <keyword> true </keyword>
+      </term>
+    </expression>
+    <symbol> ) </symbol>
+    <symbol> { </symbol>
+    <statements>
+      <doStatement>
+        <keyword> do </keyword>
+        <identifier> draw </identifier>
+        <symbol> ( </symbol>
+        <expressionList>
+        </expressionList>
+        <symbol> ) </symbol>
+        <symbol> ; </symbol>
+      </doStatement>
+    </statements>
+    <symbol> } </symbol>
+  </whileStatement>
+</statements>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce, b := initEngine(tt.s)
+			if err := ce.compileStatements(); err != nil {
+				t.Fatalf("ce.compileStatements() error = %v", err)
+			}
+			ce.e.Flush()
+			if got, want := b.String(), trimXML(tt.want); got != want {
+				t.Errorf("ce.compileStatements() = %v", got)
+				t.Errorf("wantXml = %v", want)
+			}
+		})
+	}
+}
+
 func TestCompilationEngine_compileExpression(t *testing.T) {
 	tests := []struct {
 		name    string
-		t       token.Token
+		s       string
 		want    string
-		wantErr error
+		wantErr bool
 	}{
 		{
 			"test (op term)* is not exist.",
-			token.Token{
-				TokenType: token.KEYWORD,
-				Keyword:   "class",
-			},
+			"x",
+			`
+<expression>
+  <term>
+    <identifier> x </identifier>
+  </term>
+</expression>
+`,
+			false,
+		},
+		{
+			"test (op term)*",
+			"1 + y",
 			`
 <expression>
-  <term></term>
+  <term>
+    <integerConstant> 1 </integerConstant>
+  </term>
+  <symbol> + </symbol>
+  <term>
+    <identifier> y </identifier>
+  </term>
 </expression>
 `,
-			nil,
+			false,
+		},
+		{
+			"test op without term",
+			"1 +",
+			`
+<expression>
+  <term>
+    <integerConstant> 1 </integerConstant>
+  </term>
+  <symbol> + </symbol>
+  <term>
+`,
+			true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var b bytes.Buffer
-			e := xml.NewEncoder(&b)
-			e.Indent("", "  ")
-			ce := New(tt.t, e)
-			wantErr := ce.compileExpression()
-			e.Flush()
-			got := b.String()
-			want := strings.TrimRight(strings.TrimLeft(tt.want, "\n"), "\n")
-
-			if !reflect.DeepEqual(got, want) {
+			ce, b := initEngine(tt.s)
+			err := ce.compileExpression()
+			ce.e.Flush()
+			if got, want := b.String(), trimXML(tt.want); got != want {
 				t.Errorf("ce.compileExpression() = %v", got)
 				t.Errorf("wantXml = %v", want)
 			}
-			if wantErr != tt.wantErr {
-				t.Errorf("ce.compileExpression() error got = %v, want = %v ", wantErr, tt.wantErr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ce.compileExpression() error got = %v, wantErr = %v ", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompilationEngine_compileTerm(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"stringConstant",
+			`"hoge"`,
+			`
+<term>
+  <stringConstant> hoge </stringConstant>
+</term>
+`,
+		},
+		{
+			"keywordConstant",
+			"this",
+			`
+<term>
+  <keyword> this </keyword>
+</term>
+`,
+		},
+		{
+			"varName '[' expression ']'",
+			"a[1]",
+			`
+<term>
+  <identifier> a </identifier>
+  <symbol> [ </symbol>
+  <expression>
+    <term>
+      <integerConstant> 1 </integerConstant>
+    </term>
+  </expression>
+  <symbol> ] </symbol>
+</term>
+`,
+		},
+		{
+			"subroutineCall",
+			"Math.max(1, x)",
+			`
+<term>
+  <identifier> Math </identifier>
+  <symbol> . </symbol>
+  <identifier> max </identifier>
+  <symbol> ( </symbol>
+  <expressionList>
+    <expression>
+      <term>
+        <integerConstant> 1 </integerConstant>
+      </term>
+    </expression>
+    <symbol> , </symbol>
+    <expression>
+      <term>
+        <identifier> x </identifier>
+      </term>
+    </expression>
+  </expressionList>
+  <symbol> ) </symbol>
+</term>
+`,
+		},
+		{
+			"unaryOp term",
+			"~(x)",
+			`
+<term>
+  <symbol> ~ </symbol>
+  <term>
+    <symbol> ( </symbol>
+    <expression>
+      <term>
+        <identifier> x </identifier>
+      </term>
+    </expression>
+    <symbol> ) </symbol>
+  </term>
+</term>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce, b := initEngine(tt.s)
+			if err := ce.compileTerm(); err != nil {
+				t.Fatalf("ce.compileTerm() error = %v", err)
+			}
+			ce.e.Flush()
+			if got, want := b.String(), trimXML(tt.want); got != want {
+				t.Errorf("ce.compileTerm() = %v", got)
+				t.Errorf("wantXml = %v", want)
 			}
 		})
 	}
@@ -0,0 +1,76 @@
+package cmplengn
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// NewEncoder returns xml.Encoder writing the Xxx.xml format of nand2tetris to w.
+//
+// The elements are indented by two spaces, and the end tag of an empty element
+// is on its own line as in the compare files of nand2tetris.
+// The lines end with LF while the compare files end them with CRLF.
+//
+//  <parameterList>
+//  </parameterList>
+func NewEncoder(w io.Writer) *xml.Encoder {
+	e := xml.NewEncoder(&splitWriter{w: w})
+	e.Indent("", "  ")
+	return e
+}
+
+// splitWriter writes the output of xml.Encoder to w
+// moving the end tag of an empty element to a new line with the indent of the start tag.
+// The tags are recognized by '<' and '>' since xml.Encoder escapes them in text.
+type splitWriter struct {
+	w       io.Writer
+	indent  []byte // the leading spaces of the current line
+	inLead  bool   // in the leading spaces of the current line
+	inStart bool   // in a start tag
+	empty   bool   // just after a start tag
+	lt      bool   // '<' is held until the next byte
+	prev    byte
+}
+
+func (sw *splitWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, 0, len(p)+16)
+	for _, c := range p {
+		if sw.lt {
+			sw.lt = false
+			if c == '/' && sw.empty {
+				buf = append(buf, '\n')
+				buf = append(buf, sw.indent...)
+			}
+			buf = append(buf, '<')
+			sw.inStart = c != '/' && c != '?' && c != '!'
+			sw.empty = false
+		}
+		switch {
+		case c == '<':
+			sw.lt = true
+			sw.inLead = false
+			sw.prev = c
+			continue
+		case c == '>':
+			sw.empty = sw.inStart && sw.prev != '/'
+			sw.inStart = false
+		case c == '\n':
+			sw.indent = sw.indent[:0]
+			sw.inLead = true
+			sw.empty = false
+		case sw.inLead && (c == ' ' || c == '\t'):
+			sw.indent = append(sw.indent, c)
+		default:
+			sw.inLead = false
+			if !sw.inStart {
+				sw.empty = false
+			}
+		}
+		buf = append(buf, c)
+		sw.prev = c
+	}
+	if _, err := sw.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
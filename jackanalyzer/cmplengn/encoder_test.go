@@ -0,0 +1,75 @@
+package cmplengn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestNewEncoder(t *testing.T) {
+	type elem struct {
+		XMLName xml.Name
+		Text    string `xml:",chardata"`
+	}
+	type tree struct {
+		XMLName xml.Name `xml:"statements"`
+		Sym     string   `xml:"symbol"`
+		Empty   struct{} `xml:"expressionList"`
+		Elems   []elem
+	}
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			"empty",
+			struct {
+				XMLName xml.Name `xml:"parameterList"`
+			}{},
+			"<parameterList>\n</parameterList>",
+		},
+		{
+			"nested",
+			tree{
+				Sym:   " &lt; ",
+				Elems: []elem{{XMLName: xml.Name{Local: "statements"}}, {XMLName: xml.Name{Local: "term"}, Text: "</a>"}},
+			},
+			"<statements>\n" +
+				"  <symbol> &amp;lt; </symbol>\n" +
+				"  <expressionList>\n" +
+				"  </expressionList>\n" +
+				"  <statements>\n" +
+				"  </statements>\n" +
+				"  <term>&lt;/a&gt;</term>\n" +
+				"</statements>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := NewEncoder(&b).Encode(tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+
+			// xml.Encoder may flush at any byte
+			var got bytes.Buffer
+			sw := &splitWriter{w: &got}
+			var plain bytes.Buffer
+			e := xml.NewEncoder(&plain)
+			e.Indent("", "  ")
+			if err := e.Encode(tt.v); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range plain.Bytes() {
+				sw.Write([]byte{c})
+			}
+			if got.String() != tt.want {
+				t.Errorf("Write() byte by byte = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
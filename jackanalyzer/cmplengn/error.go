@@ -0,0 +1,38 @@
+package cmplengn
+
+import (
+	"fmt"
+	"jackanalyzer/token"
+)
+
+// Error is a syntax error.
+type Error struct {
+	Pos      token.Pos
+	Msg      string
+	Expected []string // the tokens which are expected at Pos
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorList is the list of the syntax errors in the order of the source.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns nil if l is empty, l itself otherwise.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
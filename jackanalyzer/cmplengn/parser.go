@@ -0,0 +1,897 @@
+package cmplengn
+
+import (
+	"fmt"
+	"io"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+)
+
+// tokenSource is the token stream for parser.
+// *tokenizer.Scanner implements tokenSource.
+type tokenSource interface {
+	Next() (token.Token, error)
+	Peek(n int) (token.Token, error)
+}
+
+// listSource is tokenSource over the token list.
+type listSource struct {
+	t *token.Token // next token
+}
+
+func (ls *listSource) Next() (token.Token, error) {
+	t, err := ls.Peek(0)
+	if err == nil {
+		ls.t = ls.t.Next
+	}
+	return t, err
+}
+
+func (ls *listSource) Peek(n int) (token.Token, error) {
+	t := ls.t
+	for ; t != nil && n > 0; n-- {
+		t = t.Next
+	}
+	if t == nil {
+		return token.Token{}, io.EOF
+	}
+	nt := *t
+	nt.Next = nil
+	return nt, nil
+}
+
+// parser builds element from the token stream.
+//
+// The syntax error is collected to errs, and the parser resynchronises
+// at the next statement or declaration to find the following errors.
+type parser struct {
+	src  tokenSource
+	tok  token.Token // current token. zero value after the last token.
+	last token.Pos   // position of the last consumed token for the error at EOF
+	err  error       // error of src except io.EOF
+	errs ErrorList
+}
+
+// Parse parses the tokens following head and returns the class.
+//
+// head is the head of the token list returned by Tokenizer.Tokenize.
+// On syntax errors, it returns the partial class without the broken statements
+// and declarations, and ErrorList of all the errors.
+func Parse(head *token.Token) (*element.Class, error) {
+	return parse(&listSource{t: head.Next})
+}
+
+// ParseScanner parses the tokens read from s and returns the class like Parse.
+// It returns *tokenizer.Error if s fails to tokenize the source.
+func ParseScanner(s *tokenizer.Scanner) (*element.Class, error) {
+	return parse(s)
+}
+
+func parse(src tokenSource) (*element.Class, error) {
+	p := &parser{src: src}
+	p.next()
+	cl, err := p.parseClass()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err == nil && p.tok.TokenType != 0 {
+		p.error([]string{"EOF"}, "unexpected %s after class", describe(p.tok))
+	}
+	return cl, p.errs.Err()
+}
+
+// Parse Class.
+//
+//  'class' className '{' classVarDec* subroutineDec* '}'
+//
+// The broken classVarDec and subroutineDec are skipped until the next declaration,
+// keeping the partial subroutineDec whose subroutineName is parsed.
+// It returns the partial class with the error after the class header.
+func (p *parser) parseClass() (*element.Class, error) {
+	doc := p.doc()
+	if err := p.expectKeyword(token.CLASS); err != nil {
+		return nil, err
+	}
+	cn, cnPos, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return nil, err
+	}
+	cl := &element.Class{
+		Modi:   token.CLASS,
+		Cn:     element.NewIdentifier(cn),
+		LBrace: "{",
+		CnPos:  cnPos,
+		Doc:    doc,
+	}
+	for p.isKeyword(token.STATIC, token.FIELD) {
+		cvd, err := p.parseClassVarDec()
+		if err != nil {
+			p.syncDeclaration()
+			continue
+		}
+		cl.Cvds = append(cl.Cvds, cvd)
+	}
+	for p.isKeyword(token.CONSTRUCTOR, token.FUNCTION, token.METHOD) {
+		sd, err := p.parseSubroutineDec()
+		if sd != nil {
+			cl.Sds = append(cl.Sds, sd)
+		}
+		if err != nil {
+			p.syncDeclaration()
+		}
+	}
+	cl.RBracePos = p.cur().Pos
+	if err := p.expectSymbol("}"); err != nil {
+		return cl, err
+	}
+	cl.RBrace = "}"
+	return cl, nil
+}
+
+// Parse ClassVarDec.
+//
+//  ( 'static' | 'field' ) type varName (',' varName)* ';'
+func (p *parser) parseClassVarDec() (*element.ClassVarDec, error) {
+	modi, doc := p.cur().Keyword, p.doc()
+	if err := p.expectKeyword(token.STATIC, token.FIELD); err != nil {
+		return nil, err
+	}
+	vt, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	vn, vnPos, vns, err := p.parseVarNames()
+	if err != nil {
+		return nil, err
+	}
+	return &element.ClassVarDec{
+		Modi:  element.NewKeyword(string(modi)),
+		Vt:    vt,
+		Vn:    element.NewIdentifier(vn),
+		Vns:   vns,
+		Sc:    ";",
+		VnPos: vnPos,
+		Doc:   doc,
+	}, nil
+}
+
+// Parse SubroutineDec.
+//
+//  ( 'constructor' | 'function' | 'method' )
+//  ( 'void' | type ) subroutineName '(' parameterList ')'
+//  subroutineBody
+//
+// On an error after subroutineName, it returns the partial SubroutineDec with the error.
+// The body is parsed even if the parameterList is broken, when '{' follows it.
+func (p *parser) parseSubroutineDec() (*element.SubroutineDec, error) {
+	modi, doc := p.cur().Keyword, p.doc()
+	if err := p.expectKeyword(token.CONSTRUCTOR, token.FUNCTION, token.METHOD); err != nil {
+		return nil, err
+	}
+	var st element.Types
+	if p.isKeyword(token.VOID) {
+		p.next()
+		st = element.NewKeyword(string(token.VOID))
+	} else {
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		st = t
+	}
+	sn, snPos, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	sd := &element.SubroutineDec{
+		Modi:  element.NewKeyword(string(modi)),
+		St:    st,
+		Sn:    element.NewIdentifier(sn),
+		SnPos: snPos,
+		Doc:   doc,
+	}
+	err = p.parseParams(sd)
+	if err != nil && !p.isSymbol("{") {
+		return sd, err
+	}
+	sb, bodyErr := p.parseSubroutineBody()
+	if sb != nil {
+		sd.Sb = *sb
+	}
+	if err == nil {
+		err = bodyErr
+	}
+	return sd, err
+}
+
+// parseParams parses '(' parameterList ')' of sd.
+// It sets the parameters parsed before an error, and skips ')' after a broken parameterList.
+func (p *parser) parseParams(sd *element.SubroutineDec) error {
+	if err := p.expectSymbol("("); err != nil {
+		return err
+	}
+	sd.LP = "("
+	pl, err := p.parseParameterList()
+	sd.Pl = pl
+	if err != nil {
+		if p.isSymbol(")") {
+			p.next()
+			sd.RP = ")"
+		}
+		return err
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return err
+	}
+	sd.RP = ")"
+	return nil
+}
+
+// Parse ParameterList.
+// It returns nil for the empty parameterList,
+// and the parameters before the error with the error.
+//
+//  ( type varName (',' type varName)* )?
+func (p *parser) parseParameterList() (*element.ParameterList, error) {
+	if p.isSymbol(")") {
+		return nil, nil
+	}
+	vt, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	vn, vnPos, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	pl := &element.ParameterList{
+		Type:  vt,
+		Vn:    element.NewIdentifier(vn),
+		VnPos: vnPos,
+	}
+	for p.isSymbol(",") {
+		p.next()
+		vt, err := p.parseType()
+		if err != nil {
+			return pl, err
+		}
+		vn, vnPos, err := p.expectIdentifier()
+		if err != nil {
+			return pl, err
+		}
+		pl.Next = append(pl.Next, &element.NextParam{
+			Comma: ",",
+			Type:  vt,
+			Vn:    element.NewIdentifier(vn),
+			VnPos: vnPos,
+		})
+	}
+	return pl, nil
+}
+
+// Parse SubroutineBody.
+// It returns the partial SubroutineBody with the error if '}' is missing.
+//
+//  '{' varDec* statements '}'
+func (p *parser) parseSubroutineBody() (*element.SubroutineBody, error) {
+	if err := p.expectSymbol("{"); err != nil {
+		return nil, err
+	}
+	sb := &element.SubroutineBody{LB: "{"}
+	for p.isKeyword(token.VAR) {
+		vd, err := p.parseVarDec()
+		if err != nil {
+			p.syncStatement()
+			continue
+		}
+		sb.Vd = append(sb.Vd, vd)
+	}
+	sb.Stmts = p.parseStatements()
+	sb.RBPos = p.cur().Pos
+	if err := p.expectSymbol("}"); err != nil {
+		return sb, err
+	}
+	sb.RB = "}"
+	return sb, nil
+}
+
+// Parse VarDec.
+//
+//  'var' type varName (',' varName)* ';'
+func (p *parser) parseVarDec() (*element.VarDec, error) {
+	if err := p.expectKeyword(token.VAR); err != nil {
+		return nil, err
+	}
+	vt, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	vn, vnPos, vns, err := p.parseVarNames()
+	if err != nil {
+		return nil, err
+	}
+	return &element.VarDec{
+		Modi:  token.VAR,
+		Vt:    vt,
+		Vn:    element.NewIdentifier(vn),
+		Vns:   vns,
+		Sc:    ";",
+		VnPos: vnPos,
+	}, nil
+}
+
+// parseVarNames parses varName (',' varName)* ';' of classVarDec and varDec.
+// It returns the first varName and its position.
+func (p *parser) parseVarNames() (string, token.Pos, []*element.NextVns, error) {
+	vn, vnPos, err := p.expectIdentifier()
+	if err != nil {
+		return "", token.Pos{}, nil, err
+	}
+	var vns []*element.NextVns
+	for p.isSymbol(",") {
+		p.next()
+		id, pos, err := p.expectIdentifier()
+		if err != nil {
+			return "", token.Pos{}, nil, err
+		}
+		vns = append(vns, &element.NextVns{Comma: ",", Vn: element.NewIdentifier(id), VnPos: pos})
+	}
+	if err := p.expectSymbol(";"); err != nil {
+		return "", token.Pos{}, nil, err
+	}
+	return vn, vnPos, vns, nil
+}
+
+// parseType parses type.
+// The keyword type is element.NewKeyword, className is element.NewIdentifier.
+//
+//  'int' | 'char' | 'boolean' | className
+func (p *parser) parseType() (element.Types, error) {
+	cur := p.cur()
+	if cur.TokenType == token.IDENTIFIER {
+		p.next()
+		return element.NewIdentifier(cur.Identifier), nil
+	}
+	if !p.isKeyword(token.INT, token.CHAR, token.BOOLEAN) {
+		return nil, p.error(typeTokens, "expected type, got %s", describe(cur))
+	}
+	p.next()
+	return element.NewKeyword(string(cur.Keyword)), nil
+}
+
+// Parse Statements.
+// The broken statement is skipped until the next statement,
+// and a varDec among the statements is reported and skipped.
+//
+//  statement*
+func (p *parser) parseStatements() []element.Statement {
+	var stmts []element.Statement
+	for {
+		var stmt element.Statement
+		var err error
+		switch {
+		case p.isKeyword(token.LET):
+			stmt, err = p.parseLet()
+		case p.isKeyword(token.IF):
+			stmt, err = p.parseIf()
+		case p.isKeyword(token.WHILE):
+			stmt, err = p.parseWhile()
+		case p.isKeyword(token.DO):
+			stmt, err = p.parseDo()
+		case p.isKeyword(token.RETURN):
+			stmt, err = p.parseReturn()
+		case p.isKeyword(token.VAR):
+			// varDec must precede the statements: it is reported and skipped
+			p.error(statementTokens, "unexpected %s after statements", describe(p.cur()))
+			if _, err := p.parseVarDec(); err != nil {
+				p.syncStatement()
+			}
+			continue
+		default:
+			return stmts
+		}
+		if err != nil {
+			p.syncStatement()
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+// Parse Let.
+//
+//  'let' varName ( '[' expression ']' )? '=' expression ';'
+func (p *parser) parseLet() (*element.LetStatement, error) {
+	pos := p.cur().Pos
+	if err := p.expectKeyword(token.LET); err != nil {
+		return nil, err
+	}
+	vn, vnPos, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	ls := &element.LetStatement{
+		Modi:  token.LET,
+		Vn:    element.NewIdentifier(vn),
+		Pos:   pos,
+		VnPos: vnPos,
+	}
+	if p.isSymbol("[") {
+		p.next()
+		if ls.Lexp, err = p.parseExpression(); err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol("]"); err != nil {
+			return nil, err
+		}
+		ls.LB, ls.RB = "[", "]"
+	}
+	if err := p.expectSymbol("="); err != nil {
+		return nil, err
+	}
+	ls.Eq = "="
+	exp, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	ls.Rexp = *exp
+	if err := p.expectSymbol(";"); err != nil {
+		return nil, err
+	}
+	ls.Sc = ";"
+	return ls, nil
+}
+
+// Parse If.
+//
+//  'if' '(' expression ')' '{' statements '}'
+//  ( 'else' '{' statements '}' )?
+func (p *parser) parseIf() (*element.IfStatement, error) {
+	pos := p.cur().Pos
+	if err := p.expectKeyword(token.IF); err != nil {
+		return nil, err
+	}
+	exp, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	stmts, rbPos, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	is := &element.IfStatement{
+		Modi:  token.IF,
+		LP:    "(",
+		LExp:  *exp,
+		RP:    ")",
+		LB:    "{",
+		Stmts: stmts,
+		RB:    "}",
+		Pos:   pos,
+		RBPos: rbPos,
+	}
+	if p.isKeyword(token.ELSE) {
+		p.next()
+		if is.EStmts, is.ERBPos, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+		is.Else, is.ELB, is.ERB = token.ELSE, "{", "}"
+	}
+	return is, nil
+}
+
+// Parse While.
+//
+//  'while' '(' expression ')' '{' statements '}'
+func (p *parser) parseWhile() (*element.WhileStatement, error) {
+	pos := p.cur().Pos
+	if err := p.expectKeyword(token.WHILE); err != nil {
+		return nil, err
+	}
+	exp, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	stmts, rbPos, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &element.WhileStatement{
+		Modi:  token.WHILE,
+		LP:    "(",
+		Exp:   *exp,
+		RP:    ")",
+		LB:    "{",
+		Stmts: stmts,
+		RB:    "}",
+		Pos:   pos,
+		RBPos: rbPos,
+	}, nil
+}
+
+// parseCondition parses '(' expression ')' of if and while.
+func (p *parser) parseCondition() (*element.Expression, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	exp, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+// parseBlock parses '{' statements '}' of if, else and while.
+// It returns the position of '}' with the statements.
+func (p *parser) parseBlock() ([]element.Statement, token.Pos, error) {
+	if err := p.expectSymbol("{"); err != nil {
+		return nil, token.Pos{}, err
+	}
+	stmts := p.parseStatements()
+	rbPos := p.cur().Pos
+	if err := p.expectSymbol("}"); err != nil {
+		return nil, token.Pos{}, err
+	}
+	return stmts, rbPos, nil
+}
+
+// Parse Do.
+//
+//  'do' subroutineCall ';'
+func (p *parser) parseDo() (*element.DoStatement, error) {
+	pos := p.cur().Pos
+	if err := p.expectKeyword(token.DO); err != nil {
+		return nil, err
+	}
+	sub, err := p.parseSubroutineCall()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol(";"); err != nil {
+		return nil, err
+	}
+	return &element.DoStatement{
+		Modi: token.DO,
+		Sub:  sub,
+		Sc:   ";",
+		Pos:  pos,
+	}, nil
+}
+
+// Parse Return.
+//
+//  'return' expression? ';'
+func (p *parser) parseReturn() (*element.ReturnStatement, error) {
+	pos := p.cur().Pos
+	if err := p.expectKeyword(token.RETURN); err != nil {
+		return nil, err
+	}
+	rs := &element.ReturnStatement{Modi: token.RETURN, Pos: pos}
+	if !p.isSymbol(";") {
+		exp, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		rs.Exp = exp
+	}
+	if err := p.expectSymbol(";"); err != nil {
+		return nil, err
+	}
+	rs.Sc = ";"
+	return rs, nil
+}
+
+// Parse Expression.
+//
+//  term (op term)*
+func (p *parser) parseExpression() (*element.Expression, error) {
+	t, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	exp := &element.Expression{Term: t}
+	for p.tok.IsOp() {
+		bop := p.tok
+		p.next()
+		t, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		exp.Next = append(exp.Next, &element.BopTerm{
+			Bop:    element.NewSymbol(bop.Symbol),
+			Term:   t,
+			BopPos: bop.Pos,
+		})
+	}
+	return exp, nil
+}
+
+// Parse Term.
+//
+//  integerConstant | stringConstant | keywordConstant | varName | varName '[' expression ']' | subroutineCall | '(' expression ')' | unaryOp term
+//
+//  unaryOp: '-' | '~'
+func (p *parser) parseTerm() (element.Term, error) {
+	cur := p.cur()
+	switch cur.TokenType {
+	case token.INT_CONST:
+		p.next()
+		return element.NewIntegerConstant(cur.IntVal), nil
+	case token.STRING_CONST:
+		p.next()
+		return element.NewStringConstant(cur.StringVal), nil
+	case token.KEYWORD:
+		if err := p.expectKeyword(token.TRUE, token.FALSE, token.NULL, token.THIS); err != nil {
+			return nil, err
+		}
+		return &element.KeywordConstant{V: element.NewKeyword(string(cur.Keyword))}, nil
+	case token.IDENTIFIER:
+		switch nxt := p.peek(); {
+		case nxt.TokenType == token.SYMBOL && nxt.Symbol == "[":
+			p.next()
+			p.next()
+			exp, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol("]"); err != nil {
+				return nil, err
+			}
+			return &element.CallIndex{
+				Vn:    element.NewIdentifier(cur.Identifier),
+				LB:    "[",
+				Exp:   *exp,
+				RB:    "]",
+				VnPos: cur.Pos,
+			}, nil
+		case nxt.TokenType == token.SYMBOL && (nxt.Symbol == "(" || nxt.Symbol == "."):
+			return p.parseSubroutineCall()
+		default:
+			p.next()
+			return &element.VarName{V: element.NewIdentifier(cur.Identifier), Pos: cur.Pos}, nil
+		}
+	case token.SYMBOL:
+		switch cur.Symbol {
+		case "(":
+			p.next()
+			exp, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return &element.Args{LP: "(", Exp: *exp, RP: ")"}, nil
+		case "-", "~":
+			p.next()
+			t, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			return &element.UopTerm{Uop: element.NewSymbol(cur.Symbol), Term: t, UopPos: cur.Pos}, nil
+		}
+	}
+	return nil, p.error(termTokens, "expected term, got %s", describe(cur))
+}
+
+// Parse SubroutineCall.
+//
+//  subroutineName '(' expressionList ')' | (className | varName) '.' subroutineName '(' expressionList ')'
+func (p *parser) parseSubroutineCall() (*element.SubroutineCall, error) {
+	id, pos, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	sbc := &element.SubroutineCall{Pos: pos}
+	if p.isSymbol(".") {
+		p.next()
+		sbc.Name, sbc.Dot = element.NewIdentifier(id), "."
+		if id, _, err = p.expectIdentifier(); err != nil {
+			return nil, err
+		}
+	}
+	sbc.Sn = element.NewIdentifier(id)
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	if sbc.ExpL, err = p.parseExpressionList(); err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	sbc.LP, sbc.RP = "(", ")"
+	return sbc, nil
+}
+
+// Parse ExpressionList.
+//
+//  (expression (',' expression)* )?
+func (p *parser) parseExpressionList() ([]element.Expression, error) {
+	if p.isSymbol(")") {
+		return nil, nil
+	}
+	var expl []element.Expression
+	for {
+		exp, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		expl = append(expl, *exp)
+		if !p.isSymbol(",") {
+			return expl, nil
+		}
+		p.next()
+	}
+}
+
+// expectKeyword consumes the current token if it is one of kws.
+func (p *parser) expectKeyword(kws ...token.Keyword) error {
+	if !p.isKeyword(kws...) {
+		expected := make([]string, len(kws))
+		for i, v := range kws {
+			expected[i] = string(v)
+		}
+		return p.error(expected, "expected %v, got %s", kws, describe(p.cur()))
+	}
+	p.next()
+	return nil
+}
+
+// expectSymbol consumes the current token if it is the symbol s.
+func (p *parser) expectSymbol(s string) error {
+	if !p.isSymbol(s) {
+		return p.error([]string{s}, "expected '%s', got %s", s, describe(p.cur()))
+	}
+	p.next()
+	return nil
+}
+
+// expectIdentifier consumes the current token if it is an identifier.
+// It returns the identifier and its position.
+func (p *parser) expectIdentifier() (string, token.Pos, error) {
+	cur := p.cur()
+	if cur.TokenType != token.IDENTIFIER {
+		return "", token.Pos{}, p.error([]string{"identifier"}, "expected identifier, got %s", describe(cur))
+	}
+	p.next()
+	return cur.Identifier, cur.Pos, nil
+}
+
+var (
+	typeTokens = []string{"int", "char", "boolean", "identifier"}
+	termTokens = []string{
+		"integerConstant", "stringConstant", "true", "false", "null", "this",
+		"identifier", "(", "-", "~",
+	}
+	statementKeywords   = []token.Keyword{token.LET, token.IF, token.WHILE, token.DO, token.RETURN}
+	statementTokens     = []string{"let", "if", "while", "do", "return", "}"}
+	declarationKeywords = []token.Keyword{
+		token.STATIC, token.FIELD, token.CONSTRUCTOR, token.FUNCTION, token.METHOD,
+	}
+)
+
+// error records the syntax error at the current token and returns it.
+// The error at EOF is positioned at the last token.
+func (p *parser) error(expected []string, format string, args ...interface{}) error {
+	pos := p.tok.Pos
+	if p.tok.TokenType == 0 {
+		pos = p.last
+	}
+	e := &Error{
+		Pos:      pos,
+		Msg:      fmt.Sprintf(format, args...),
+		Expected: expected,
+	}
+	p.errs = append(p.errs, e)
+	return e
+}
+
+// syncStatement skips the tokens to the next statement or varDec.
+// It stops after ';' or before a statement keyword, 'var', '}' or a declaration keyword,
+// skipping the nested blocks.
+func (p *parser) syncStatement() {
+	depth := 0
+	for p.tok.TokenType != 0 {
+		switch {
+		case p.isKeyword(declarationKeywords...):
+			return
+		case p.isSymbol("{"):
+			depth++
+		case p.isSymbol("}"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case depth == 0 && p.isSymbol(";"):
+			p.next()
+			return
+		case depth == 0 && (p.isKeyword(statementKeywords...) || p.isKeyword(token.VAR)):
+			return
+		}
+		p.next()
+	}
+}
+
+// syncDeclaration skips the tokens to the next classVarDec or subroutineDec.
+// It stops before a declaration keyword or '}' which closes the class,
+// skipping the subroutine bodies.
+func (p *parser) syncDeclaration() {
+	depth := 0
+	for p.tok.TokenType != 0 {
+		switch {
+		case p.isKeyword(declarationKeywords...):
+			return
+		case p.isSymbol("{"):
+			depth++
+		case p.isSymbol("}"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		p.next()
+	}
+}
+
+// next makes the next token of src the current token.
+func (p *parser) next() {
+	if p.tok.TokenType != 0 {
+		p.last = p.tok.Pos
+	}
+	t, err := p.src.Next()
+	if err != nil {
+		if err != io.EOF && p.err == nil {
+			p.err = err
+		}
+		t = token.Token{}
+	}
+	p.tok = t
+}
+
+// peek returns the token following the current token.
+func (p *parser) peek() token.Token {
+	t, err := p.src.Peek(0)
+	if err != nil {
+		return token.Token{}
+	}
+	return t
+}
+
+// cur returns the current token. It returns zero value after the last token.
+func (p *parser) cur() token.Token {
+	return p.tok
+}
+
+// doc returns the last '/** */' comment in the leading trivia of the current token.
+func (p *parser) doc() string {
+	for i := len(p.tok.Leading) - 1; i >= 0; i-- {
+		if p.tok.Leading[i].IsDoc() {
+			return p.tok.Leading[i].Text
+		}
+	}
+	return ""
+}
+
+func (p *parser) isKeyword(kws ...token.Keyword) bool {
+	if p.tok.TokenType != token.KEYWORD {
+		return false
+	}
+	for _, v := range kws {
+		if p.tok.Keyword == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) isSymbol(s string) bool {
+	return p.tok.TokenType == token.SYMBOL && p.tok.Symbol == s
+}
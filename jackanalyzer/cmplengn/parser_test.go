@@ -0,0 +1,715 @@
+package cmplengn
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want *element.Class
+	}{
+		{
+			"empty class",
+			"class Main {}",
+			&element.Class{
+				Modi:   "class",
+				Cn:     "Main",
+				LBrace: "{",
+				RBrace: "}",
+			},
+		},
+		{
+			"declarations",
+			`
+class Main {
+	static boolean b;
+	field int x, y;
+	method void set(int Ax, Point p) {
+		var Array a, c;
+		return;
+	}
+}
+`,
+			&element.Class{
+				Modi:   "class",
+				Cn:     "Main",
+				LBrace: "{",
+				Cvds: []*element.ClassVarDec{
+					{
+						Modi: "static",
+						Vt:   element.NewKeyword("boolean"),
+						Vn:   "b",
+						Sc:   ";",
+					},
+					{
+						Modi: "field",
+						Vt:   element.NewKeyword("int"),
+						Vn:   "x",
+						Vns: []*element.NextVns{
+							{Comma: ",", Vn: "y"},
+						},
+						Sc: ";",
+					},
+				},
+				Sds: []*element.SubroutineDec{
+					{
+						Modi: "method",
+						St:   element.NewKeyword("void"),
+						Sn:   "set",
+						LP:   "(",
+						Pl: &element.ParameterList{
+							Type: element.NewKeyword("int"),
+							Vn:   "Ax",
+							Next: []*element.NextParam{
+								{
+									Comma: ",",
+									Type:  element.NewIdentifier("Point"),
+									Vn:    "p",
+								},
+							},
+						},
+						RP: ")",
+						Sb: element.SubroutineBody{
+							LB: "{",
+							Vd: []*element.VarDec{
+								{
+									Modi: "var",
+									Vt:   element.NewIdentifier("Array"),
+									Vn:   "a",
+									Vns: []*element.NextVns{
+										{Comma: ",", Vn: "c"},
+									},
+									Sc: ";",
+								},
+							},
+							Stmts: []element.Statement{
+								&element.ReturnStatement{
+									Modi: "return",
+									Sc:   ";",
+								},
+							},
+							RB: "}",
+						},
+					},
+				},
+				RBrace: "}",
+			},
+		},
+		{
+			"class types",
+			`
+class SquareGame {
+	field Square square;
+	method Point add(Point p) {
+		return p;
+	}
+}
+`,
+			&element.Class{
+				Modi:   "class",
+				Cn:     "SquareGame",
+				LBrace: "{",
+				Cvds: []*element.ClassVarDec{
+					{
+						Modi: "field",
+						Vt:   element.NewIdentifier("Square"),
+						Vn:   "square",
+						Sc:   ";",
+					},
+				},
+				Sds: []*element.SubroutineDec{
+					{
+						Modi: "method",
+						St:   element.NewIdentifier("Point"),
+						Sn:   "add",
+						LP:   "(",
+						Pl: &element.ParameterList{
+							Type: element.NewIdentifier("Point"),
+							Vn:   "p",
+						},
+						RP: ")",
+						Sb: element.SubroutineBody{
+							LB: "{",
+							Stmts: []element.Statement{
+								&element.ReturnStatement{
+									Modi: "return",
+									Exp: &element.Expression{
+										Term: &element.VarName{V: "p"},
+									},
+									Sc: ";",
+								},
+							},
+							RB: "}",
+						},
+					},
+				},
+				RBrace: "}",
+			},
+		},
+		{
+			"statements",
+			`
+class Main {
+	function int main() {
+		let a[1] = -x + 2;
+		if (x) { do Output.printInt(x, "s"); } else { while (true) {} }
+		do draw();
+		return (null);
+	}
+}
+`,
+			&element.Class{
+				Modi:   "class",
+				Cn:     "Main",
+				LBrace: "{",
+				Sds: []*element.SubroutineDec{
+					{
+						Modi: "function",
+						St:   element.NewKeyword("int"),
+						Sn:   "main",
+						LP:   "(",
+						RP:   ")",
+						Sb: element.SubroutineBody{
+							LB: "{",
+							Stmts: []element.Statement{
+								&element.LetStatement{
+									Modi: "let",
+									Vn:   "a",
+									LB:   "[",
+									Lexp: &element.Expression{
+										Term: element.NewIntegerConstant(1),
+									},
+									RB: "]",
+									Eq: "=",
+									Rexp: element.Expression{
+										Term: &element.UopTerm{
+											Uop:  "-",
+											Term: &element.VarName{V: "x"},
+										},
+										Next: []*element.BopTerm{
+											{
+												Bop:  "+",
+												Term: element.NewIntegerConstant(2),
+											},
+										},
+									},
+									Sc: ";",
+								},
+								&element.IfStatement{
+									Modi: "if",
+									LP:   "(",
+									LExp: element.Expression{
+										Term: &element.VarName{V: "x"},
+									},
+									RP: ")",
+									LB: "{",
+									Stmts: []element.Statement{
+										&element.DoStatement{
+											Modi: "do",
+											Sub: &element.SubroutineCall{
+												Name: "Output",
+												Dot:  ".",
+												Sn:   "printInt",
+												LP:   "(",
+												ExpL: []element.Expression{
+													{Term: &element.VarName{V: "x"}},
+													{Term: element.NewStringConstant("s")},
+												},
+												RP: ")",
+											},
+											Sc: ";",
+										},
+									},
+									RB:   "}",
+									Else: "else",
+									ELB:  "{",
+									EStmts: []element.Statement{
+										&element.WhileStatement{
+											Modi: "while",
+											LP:   "(",
+											Exp: element.Expression{
+												Term: &element.KeywordConstant{V: "true"},
+											},
+											RP: ")",
+											LB: "{",
+											RB: "}",
+										},
+									},
+									ERB: "}",
+								},
+								&element.DoStatement{
+									Modi: "do",
+									Sub: &element.SubroutineCall{
+										Sn: "draw",
+										LP: "(",
+										RP: ")",
+									},
+									Sc: ";",
+								},
+								&element.ReturnStatement{
+									Modi: "return",
+									Exp: &element.Expression{
+										Term: &element.Args{
+											LP: "(",
+											Exp: element.Expression{
+												Term: &element.KeywordConstant{V: "null"},
+											},
+											RP: ")",
+										},
+									},
+									Sc: ";",
+								},
+							},
+							RB: "}",
+						},
+					},
+				},
+				RBrace: "}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, err := tokenizer.New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(head)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			clearPos(reflect.ValueOf(got))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+
+			got, err = ParseScanner(tokenizer.NewScanner(strings.NewReader(tt.s)))
+			if err != nil {
+				t.Fatalf("ParseScanner() error = %v", err)
+			}
+			clearPos(reflect.ValueOf(got))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScanner() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// clearPos sets every token.Pos in v to the zero value.
+func clearPos(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPos(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPos(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(token.Pos{}) {
+			v.Set(reflect.ValueOf(token.Pos{}))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			clearPos(v.Field(i))
+		}
+	}
+}
+
+func TestParse_pos(t *testing.T) {
+	s := `class Main {
+  field int x, y;
+  method void set(int a, Point p) {
+    var Array b;
+    let x = a[1] + y;
+    do p.draw(b);
+    do draw();
+    if (x) {} else {}
+    while (y) {}
+    return;
+  }
+}
+`
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := Parse(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := cl.Sds[0]
+	ls := sd.Sb.Stmts[0].(*element.LetStatement)
+	tests := []struct {
+		name string
+		got  token.Pos
+		want string
+	}{
+		{"className", cl.CnPos, "1:7"},
+		{"classVarDec", cl.Cvds[0].VnPos, "2:13"},
+		{"classVarDec next", cl.Cvds[0].Vns[0].VnPos, "2:16"},
+		{"subroutineName", sd.SnPos, "3:15"},
+		{"parameter", sd.Pl.VnPos, "3:23"},
+		{"parameter next", sd.Pl.Next[0].VnPos, "3:32"},
+		{"varDec", sd.Sb.Vd[0].VnPos, "4:15"},
+		{"let", ls.VnPos, "5:9"},
+		{"callIndex", ls.Rexp.Term.(*element.CallIndex).VnPos, "5:13"},
+		{"varName", ls.Rexp.Next[0].Term.(*element.VarName).Pos, "5:20"},
+		{"subroutineCall", sd.Sb.Stmts[1].(*element.DoStatement).Sub.Pos, "6:8"},
+		{"subroutineCall without name", sd.Sb.Stmts[2].(*element.DoStatement).Sub.Pos, "7:8"},
+		{"binary operator", ls.Rexp.Next[0].BopPos, "5:18"},
+		{"let keyword", ls.Pos, "5:5"},
+		{"do", sd.Sb.Stmts[1].(*element.DoStatement).Pos, "6:5"},
+		{"if", sd.Sb.Stmts[3].(*element.IfStatement).Pos, "8:5"},
+		{"while", sd.Sb.Stmts[4].(*element.WhileStatement).Pos, "9:5"},
+		{"return", sd.Sb.Stmts[5].(*element.ReturnStatement).Pos, "10:5"},
+		{"subroutineBody '}'", sd.Sb.RBPos, "11:3"},
+		{"if '}'", sd.Sb.Stmts[3].(*element.IfStatement).RBPos, "8:13"},
+		{"else '}'", sd.Sb.Stmts[3].(*element.IfStatement).ERBPos, "8:21"},
+		{"while '}'", sd.Sb.Stmts[4].(*element.WhileStatement).RBPos, "9:16"},
+		{"class '}'", cl.RBracePos, "12:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.String(); got != tt.want {
+				t.Errorf("pos = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		wantErr string
+	}{
+		{
+			"not class",
+			"function void main() {}",
+			"1:1: expected [class], got keyword 'function'",
+		},
+		{
+			"token after class",
+			"class Main {} }",
+			"1:15: unexpected symbol '}' after class",
+		},
+		{
+			"missing semicolon",
+			"class Main { function void main() { do draw() } }",
+			"1:47: expected ';', got symbol '}'",
+		},
+		{
+			"invalid term",
+			"class Main { function void main() { let x = class; } }",
+			"1:45: expected [true false null this], got keyword 'class'",
+		},
+		{
+			"void in classVarDec",
+			"class Main { field void x; }",
+			"1:20: expected type, got keyword 'void'",
+		},
+		{
+			"EOF",
+			"class Main { function void main() {",
+			"1:35: expected '}', got EOF (and 1 more errors)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, err := tokenizer.New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Parse(head)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Parse() error got = %v, want = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_recover(t *testing.T) {
+	s := `class Main {
+  field int x
+  field int y;
+  function void main() {
+    var int a;
+    let a = ;
+    do draw();
+    var int b;
+    if (a { let a = 1; }
+    while (a) {
+      let a = a + ;
+      let x = 1;
+    }
+    return;
+  }
+  method void draw( {
+    return;
+  }
+  function void ok() {
+    return
+  }
+}
+`
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := Parse(head)
+
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Fatalf("Parse() error = %v, want ErrorList", err)
+	}
+	want := []*Error{
+		{token.Pos{Offset: 29, Line: 3, Column: 3}, "expected ';', got keyword 'field'", []string{";"}},
+		{token.Pos{Offset: 94, Line: 6, Column: 13}, "expected term, got symbol ';'", termTokens},
+		{token.Pos{Offset: 115, Line: 8, Column: 5}, "unexpected keyword 'var' after statements", statementTokens},
+		{token.Pos{Offset: 136, Line: 9, Column: 11}, "expected ')', got symbol '{'", []string{")"}},
+		{token.Pos{Offset: 185, Line: 11, Column: 19}, "expected term, got symbol ';'", termTokens},
+		{token.Pos{Offset: 246, Line: 16, Column: 21}, "expected type, got symbol '{'", typeTokens},
+		{token.Pos{Offset: 300, Line: 21, Column: 3}, "expected term, got symbol '}'", termTokens},
+	}
+	if !reflect.DeepEqual([]*Error(errs), want) {
+		for _, e := range errs {
+			t.Errorf("Parse() error = %v %#v", e, e)
+		}
+	}
+
+	// partial class
+	if cl == nil {
+		t.Fatal("Parse() class = nil")
+	}
+	if got := len(cl.Cvds); got != 1 || cl.Cvds[0].Vn != "y" {
+		t.Errorf("Parse() Cvds = %#v", cl.Cvds)
+	}
+	if got := len(cl.Sds); got != 3 || cl.Sds[0].Sn != "main" || cl.Sds[1].Sn != "draw" || cl.Sds[2].Sn != "ok" {
+		t.Fatalf("Parse() Sds = %#v", cl.Sds)
+	}
+	if got := cl.Sds[1].Sb.Stmts; len(got) != 1 {
+		t.Errorf("Parse() draw Stmts = %#v", got)
+	}
+	stmts := cl.Sds[0].Sb.Stmts
+	if len(stmts) != 3 {
+		t.Fatalf("Parse() Stmts = %#v", stmts)
+	}
+	ws, ok := stmts[1].(*element.WhileStatement)
+	if !ok || len(ws.Stmts) != 1 {
+		t.Errorf("Parse() WhileStatement = %#v", stmts[1])
+	}
+	if cl.RBrace != "}" {
+		t.Errorf("Parse() RBrace = %v", cl.RBrace)
+	}
+}
+
+func TestParse_partialSubroutine(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		wantParam []string
+		wantStmts int
+		wantErr   string
+	}{
+		{
+			"missing '}'",
+			"class Main { function void main(int a) { do draw(); return;",
+			[]string{"a"},
+			2,
+			"1:59: expected '}', got EOF (and 1 more errors)",
+		},
+		{
+			"broken parameterList",
+			"class Main { function void main(int a, int) { return; } }",
+			[]string{"a"},
+			1,
+			"1:43: expected identifier, got symbol ')'",
+		},
+		{
+			"no body",
+			"class Main { function void main(int a, int b; function void ok() { return; } }",
+			[]string{"a", "b"},
+			0,
+			"1:45: expected ')', got symbol ';'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, err := tokenizer.New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cl, err := Parse(head)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if cl == nil || len(cl.Sds) == 0 || cl.Sds[0].Sn != "main" {
+				t.Fatalf("Parse() class = %#v", cl)
+			}
+			sd := cl.Sds[0]
+			var params []string
+			if sd.Pl != nil {
+				params = append(params, string(sd.Pl.Vn))
+				for _, np := range sd.Pl.Next {
+					params = append(params, string(np.Vn))
+				}
+			}
+			if !reflect.DeepEqual(params, tt.wantParam) {
+				t.Errorf("Parse() params = %v, want %v", params, tt.wantParam)
+			}
+			if got := len(sd.Sb.Stmts); got != tt.wantStmts {
+				t.Errorf("Parse() Stmts = %d, want %d", got, tt.wantStmts)
+			}
+		})
+	}
+}
+
+func TestErrorList_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		l    ErrorList
+		want string
+	}{
+		{"empty", nil, "no errors"},
+		{"one", ErrorList{{Pos: token.Pos{Line: 1, Column: 2}, Msg: "a"}}, "1:2: a"},
+		{
+			"many",
+			ErrorList{
+				{Pos: token.Pos{Line: 1, Column: 2}, Msg: "a"},
+				{Pos: token.Pos{Line: 3, Column: 4}, Msg: "b"},
+			},
+			"1:2: a (and 1 more errors)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if err := ErrorList(nil).Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func TestParseScanner_tokenizeError(t *testing.T) {
+	_, err := ParseScanner(tokenizer.NewScanner(strings.NewReader("class Main { field int x; $")))
+	var te *tokenizer.Error
+	if !errors.As(err, &te) || te.Error() != "1:27: invalid character '$'" {
+		t.Errorf("ParseScanner() error = %v", err)
+	}
+}
+
+func TestParse_doc(t *testing.T) {
+	src := `/** The main class. */
+class Main {
+    /** A counter. */
+    static int count;
+    /* not a doc comment */
+    field int x;
+
+    /**
+     * Runs.
+     */
+    // the entry
+    function void main() {
+        return;
+    }
+
+    /** detached */ method void m() {
+        return;
+    }
+}
+`
+	cl, err := ParseScanner(tokenizer.NewScanner(strings.NewReader(src), tokenizer.WithTrivia()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{cl.Doc, cl.Cvds[0].Doc, cl.Cvds[1].Doc, cl.Sds[0].Doc, cl.Sds[1].Doc}
+	want := []string{
+		"/** The main class. */",
+		"/** A counter. */",
+		"",
+		"/**\n     * Runs.\n     */",
+		"/** detached */",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Doc = %q, want %q", got, want)
+	}
+
+	// without the trivia option the doc comments are not kept
+	cl, err = ParseScanner(tokenizer.NewScanner(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.Doc != "" || cl.Sds[0].Doc != "" {
+		t.Errorf("Doc = %q, %q, want empty", cl.Doc, cl.Sds[0].Doc)
+	}
+}
+
+// TestParse_xml compares the XML of the class parsed from the token list
+// and from the scanner with the compare files of nand2tetris.
+func TestParse_xml(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.jack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsers := []struct {
+		name  string
+		parse func(src []byte) (*element.Class, error)
+	}{
+		{"Parse", func(src []byte) (*element.Class, error) {
+			head, err := tokenizer.New(bytes.NewReader(src)).Tokenize()
+			if err != nil {
+				return nil, err
+			}
+			return Parse(head)
+		}},
+		{"ParseScanner", func(src []byte) (*element.Class, error) {
+			return ParseScanner(tokenizer.NewScanner(bytes.NewReader(src)))
+		}},
+	}
+	for _, f := range files {
+		src, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile(strings.TrimSuffix(f, ".jack") + ".xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range parsers {
+			t.Run(p.name+" "+f, func(t *testing.T) {
+				cl, err := p.parse(src)
+				if err != nil {
+					t.Fatalf("%s() error = %v", p.name, err)
+				}
+				var b bytes.Buffer
+				e := NewEncoder(&b)
+				if err := e.Encode(cl); err != nil {
+					t.Fatal(err)
+				}
+				e.Flush()
+				b.WriteString("\n")
+				if got := b.String(); got != lf(want) {
+					t.Errorf("Encode() = %v", got)
+					t.Errorf("wantXml = %v", string(want))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package codegen
+
+import (
+	"io"
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/vmwriter"
+	"strconv"
+
+	"golang.org/x/xerrors"
+)
+
+// Generator generates the VM code of the class from the element AST.
+type Generator struct {
+	vw        *vmwriter.VMWriter
+	st        *symboltable.SymbolTable
+	className string
+	ifs       int // running index of the if labels in the subroutine
+	whiles    int // running index of the while labels in the subroutine
+}
+
+// Generate writes the VM code of cl to w.
+func Generate(w io.Writer, cl *element.Class) error {
+	g := &Generator{
+		vw:        vmwriter.New(w),
+		st:        symboltable.NewClass(cl),
+		className: string(cl.Cn),
+	}
+	for _, sd := range cl.Sds {
+		if err := g.genSubroutineDec(sd); err != nil {
+			return err
+		}
+	}
+	return g.vw.Flush()
+}
+
+var segments = map[symboltable.Kind]vmwriter.Segment{
+	symboltable.STATIC: vmwriter.STATIC,
+	symboltable.FIELD:  vmwriter.THIS,
+	symboltable.ARG:    vmwriter.ARG,
+	symboltable.VAR:    vmwriter.LOCAL,
+}
+
+var bops = map[string]vmwriter.Command{
+	"+": vmwriter.ADD,
+	"-": vmwriter.SUB,
+	"&": vmwriter.AND,
+	"|": vmwriter.OR,
+	"<": vmwriter.LT,
+	">": vmwriter.GT,
+	"=": vmwriter.EQ,
+}
+
+var uops = map[string]vmwriter.Command{
+	"-": vmwriter.NEG,
+	"~": vmwriter.NOT,
+}
+
+// Generate subroutineDec.
+//
+//  function className.subroutineName nLocals
+//
+// The constructor allocates the fields by Memory.alloc,
+// the method sets argument 0 to pointer 0.
+func (g *Generator) genSubroutineDec(sd *element.SubroutineDec) error {
+	g.st.StartSubroutineDec(sd)
+	g.ifs = 0
+	g.whiles = 0
+
+	g.vw.WriteFunction(g.className+"."+string(sd.Sn), g.st.VarCount(symboltable.VAR))
+	switch sd.Modi {
+	case "constructor":
+		g.vw.WritePush(vmwriter.CONST, g.st.VarCount(symboltable.FIELD))
+		g.vw.WriteCall("Memory.alloc", 1)
+		g.vw.WritePop(vmwriter.POINTER, 0)
+	case "method":
+		g.vw.WritePush(vmwriter.ARG, 0)
+		g.vw.WritePop(vmwriter.POINTER, 0)
+	}
+	return g.genStatements(sd.Sb.Stmts)
+}
+
+func (g *Generator) genStatements(stmts []element.Statement) error {
+	for _, stmt := range stmts {
+		var err error
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			err = g.genLet(s)
+		case *element.IfStatement:
+			err = g.genIf(s)
+		case *element.WhileStatement:
+			err = g.genWhile(s)
+		case *element.DoStatement:
+			err = g.genDo(s)
+		case *element.ReturnStatement:
+			err = g.genReturn(s)
+		default:
+			err = xerrors.Errorf("unknown statement %T", stmt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate let.
+//
+// The array element is assigned through 'that' after the right expression is evaluated,
+// because the right expression may also use 'pointer 1'.
+func (g *Generator) genLet(ls *element.LetStatement) error {
+	s, err := g.lookup(string(ls.Vn))
+	if err != nil {
+		return err
+	}
+	if ls.Lexp == nil {
+		if err := g.genExpression(&ls.Rexp); err != nil {
+			return err
+		}
+		g.vw.WritePop(segments[s.Kind], s.Index)
+		return nil
+	}
+
+	g.vw.WritePush(segments[s.Kind], s.Index)
+	if err := g.genExpression(ls.Lexp); err != nil {
+		return err
+	}
+	g.vw.WriteArithmetic(vmwriter.ADD)
+	if err := g.genExpression(&ls.Rexp); err != nil {
+		return err
+	}
+	g.vw.WritePop(vmwriter.TEMP, 0)
+	g.vw.WritePop(vmwriter.POINTER, 1)
+	g.vw.WritePush(vmwriter.TEMP, 0)
+	g.vw.WritePop(vmwriter.THAT, 0)
+	return nil
+}
+
+// Generate if.
+//
+//  if-goto IF_TRUEn, goto IF_FALSEn, label IF_TRUEn, statements,
+//  (goto IF_ENDn,) label IF_FALSEn, (else statements, label IF_ENDn)
+func (g *Generator) genIf(is *element.IfStatement) error {
+	n := strconv.Itoa(g.ifs)
+	g.ifs++
+	if err := g.genExpression(&is.LExp); err != nil {
+		return err
+	}
+	g.vw.WriteIf("IF_TRUE" + n)
+	g.vw.WriteGoto("IF_FALSE" + n)
+	g.vw.WriteLabel("IF_TRUE" + n)
+	if err := g.genStatements(is.Stmts); err != nil {
+		return err
+	}
+	if is.Else == "" {
+		g.vw.WriteLabel("IF_FALSE" + n)
+		return nil
+	}
+	g.vw.WriteGoto("IF_END" + n)
+	g.vw.WriteLabel("IF_FALSE" + n)
+	if err := g.genStatements(is.EStmts); err != nil {
+		return err
+	}
+	g.vw.WriteLabel("IF_END" + n)
+	return nil
+}
+
+// Generate while.
+//
+//  label WHILE_EXPn, not expression, if-goto WHILE_ENDn,
+//  statements, goto WHILE_EXPn, label WHILE_ENDn
+func (g *Generator) genWhile(ws *element.WhileStatement) error {
+	n := strconv.Itoa(g.whiles)
+	g.whiles++
+	g.vw.WriteLabel("WHILE_EXP" + n)
+	if err := g.genExpression(&ws.Exp); err != nil {
+		return err
+	}
+	g.vw.WriteArithmetic(vmwriter.NOT)
+	g.vw.WriteIf("WHILE_END" + n)
+	if err := g.genStatements(ws.Stmts); err != nil {
+		return err
+	}
+	g.vw.WriteGoto("WHILE_EXP" + n)
+	g.vw.WriteLabel("WHILE_END" + n)
+	return nil
+}
+
+// Generate do. The returned value is discarded to temp 0.
+func (g *Generator) genDo(do *element.DoStatement) error {
+	if err := g.genSubroutineCall(do.Sub); err != nil {
+		return err
+	}
+	g.vw.WritePop(vmwriter.TEMP, 0)
+	return nil
+}
+
+// Generate return. The void subroutine returns 0.
+func (g *Generator) genReturn(rs *element.ReturnStatement) error {
+	if rs.Exp == nil {
+		g.vw.WritePush(vmwriter.CONST, 0)
+	} else if err := g.genExpression(rs.Exp); err != nil {
+		return err
+	}
+	g.vw.WriteReturn()
+	return nil
+}
+
+// Generate expression.
+//
+// The binary operators are applied from left to right.
+// '*' and '/' are Math.multiply and Math.divide.
+func (g *Generator) genExpression(exp *element.Expression) error {
+	if err := g.genTerm(exp.Term); err != nil {
+		return err
+	}
+	for _, bt := range exp.Next {
+		if err := g.genTerm(bt.Term); err != nil {
+			return err
+		}
+		switch op := string(bt.Bop); op {
+		case "*":
+			g.vw.WriteCall("Math.multiply", 2)
+		case "/":
+			g.vw.WriteCall("Math.divide", 2)
+		default:
+			cmd, ok := bops[op]
+			if !ok {
+				return xerrors.Errorf("unknown binary operator '%s'", op)
+			}
+			g.vw.WriteArithmetic(cmd)
+		}
+	}
+	return nil
+}
+
+func (g *Generator) genTerm(term element.Term) error {
+	switch t := term.(type) {
+	case *element.IntegerConstant:
+		g.vw.WritePush(vmwriter.CONST, int(t.V))
+	case *element.StringConstant:
+		g.genStringConstant(string(t.V))
+	case *element.KeywordConstant:
+		switch t.V {
+		case "true":
+			g.vw.WritePush(vmwriter.CONST, 0)
+			g.vw.WriteArithmetic(vmwriter.NOT)
+		case "false", "null":
+			g.vw.WritePush(vmwriter.CONST, 0)
+		case "this":
+			g.vw.WritePush(vmwriter.POINTER, 0)
+		default:
+			return xerrors.Errorf("unknown keyword constant '%s'", string(t.V))
+		}
+	case *element.VarName:
+		s, err := g.lookup(string(t.V))
+		if err != nil {
+			return err
+		}
+		g.vw.WritePush(segments[s.Kind], s.Index)
+	case *element.CallIndex:
+		s, err := g.lookup(string(t.Vn))
+		if err != nil {
+			return err
+		}
+		g.vw.WritePush(segments[s.Kind], s.Index)
+		if err := g.genExpression(&t.Exp); err != nil {
+			return err
+		}
+		g.vw.WriteArithmetic(vmwriter.ADD)
+		g.vw.WritePop(vmwriter.POINTER, 1)
+		g.vw.WritePush(vmwriter.THAT, 0)
+	case *element.SubroutineCall:
+		return g.genSubroutineCall(t)
+	case *element.Args:
+		return g.genExpression(&t.Exp)
+	case *element.UopTerm:
+		if err := g.genTerm(t.Term); err != nil {
+			return err
+		}
+		cmd, ok := uops[string(t.Uop)]
+		if !ok {
+			return xerrors.Errorf("unknown unary operator '%s'", string(t.Uop))
+		}
+		g.vw.WriteArithmetic(cmd)
+	default:
+		return xerrors.Errorf("unknown term %T", term)
+	}
+	return nil
+}
+
+// Generate stringConstant by String.new and String.appendChar.
+func (g *Generator) genStringConstant(s string) {
+	rs := []rune(s)
+	g.vw.WritePush(vmwriter.CONST, len(rs))
+	g.vw.WriteCall("String.new", 1)
+	for _, r := range rs {
+		g.vw.WritePush(vmwriter.CONST, int(r))
+		g.vw.WriteCall("String.appendChar", 2)
+	}
+}
+
+// Generate subroutineCall.
+//
+//  subroutineName '(' expressionList ')'               -> method of this class
+//  varName '.' subroutineName '(' expressionList ')'   -> method of the type of varName
+//  className '.' subroutineName '(' expressionList ')' -> function or constructor
+func (g *Generator) genSubroutineCall(sc *element.SubroutineCall) error {
+	var name string
+	nArgs := len(sc.ExpL)
+	switch {
+	case sc.Dot == "":
+		g.vw.WritePush(vmwriter.POINTER, 0)
+		name = g.className + "." + string(sc.Sn)
+		nArgs++
+	default:
+		if s, ok := g.st.Lookup(string(sc.Name)); ok {
+			g.vw.WritePush(segments[s.Kind], s.Index)
+			name = s.Type + "." + string(sc.Sn)
+			nArgs++
+		} else {
+			name = string(sc.Name) + "." + string(sc.Sn)
+		}
+	}
+	for i := range sc.ExpL {
+		if err := g.genExpression(&sc.ExpL[i]); err != nil {
+			return err
+		}
+	}
+	g.vw.WriteCall(name, nArgs)
+	return nil
+}
+
+func (g *Generator) lookup(name string) (*symboltable.Symbol, error) {
+	s, ok := g.st.Lookup(name)
+	if !ok {
+		return nil, xerrors.Errorf("undefined variable '%s' in class %s", name, g.className)
+	}
+	return s, nil
+}
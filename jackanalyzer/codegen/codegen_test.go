@@ -0,0 +1,248 @@
+package codegen
+
+import (
+	"bytes"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, s string) *element.Class {
+	t.Helper()
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"function and expressions",
+			`
+class Main {
+	function void main() {
+		var int x;
+		let x = 1 + (2 * 3) - -x;
+		do Output.printInt(x / 2);
+		return;
+	}
+}
+`,
+			`function Main.main 1
+push constant 1
+push constant 2
+push constant 3
+call Math.multiply 2
+add
+push local 0
+neg
+sub
+pop local 0
+push local 0
+push constant 2
+call Math.divide 2
+call Output.printInt 1
+pop temp 0
+push constant 0
+return
+`,
+		},
+		{
+			"if and while",
+			`
+class Main {
+	function int abs(int x) {
+		if (x < 0) { let x = -x; }
+		while (~(x > 10)) { let x = x + 1; }
+		if (x = 0) { return x; } else { return true; }
+	}
+}
+`,
+			`function Main.abs 0
+push argument 0
+push constant 0
+lt
+if-goto IF_TRUE0
+goto IF_FALSE0
+label IF_TRUE0
+push argument 0
+neg
+pop argument 0
+label IF_FALSE0
+label WHILE_EXP0
+push argument 0
+push constant 10
+gt
+not
+not
+if-goto WHILE_END0
+push argument 0
+push constant 1
+add
+pop argument 0
+goto WHILE_EXP0
+label WHILE_END0
+push argument 0
+push constant 0
+eq
+if-goto IF_TRUE1
+goto IF_FALSE1
+label IF_TRUE1
+push argument 0
+return
+goto IF_END1
+label IF_FALSE1
+push constant 0
+not
+return
+label IF_END1
+`,
+		},
+		{
+			"array and string",
+			`
+class Main {
+	static Array a;
+	function void main() {
+		let a[1] = a[2] & false;
+		do Output.printString("Hi");
+		return;
+	}
+}
+`,
+			`function Main.main 0
+push static 0
+push constant 1
+add
+push static 0
+push constant 2
+add
+pop pointer 1
+push that 0
+push constant 0
+and
+pop temp 0
+pop pointer 1
+push temp 0
+pop that 0
+push constant 2
+call String.new 1
+push constant 72
+call String.appendChar 2
+push constant 105
+call String.appendChar 2
+call Output.printString 1
+pop temp 0
+push constant 0
+return
+`,
+		},
+		{
+			"method and calls",
+			`
+class Point {
+	field int x, y;
+	method void move(Point p, int dx) {
+		let y = x | null;
+		do draw();
+		do p.add(dx);
+		return;
+	}
+}
+`,
+			`function Point.move 0
+push argument 0
+pop pointer 0
+push this 0
+push constant 0
+or
+pop this 1
+push pointer 0
+call Point.draw 1
+pop temp 0
+push argument 1
+push argument 2
+call Point.add 2
+pop temp 0
+push constant 0
+return
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Generate(&b, parse(t, tt.s)); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Generate() got = \n%v, want = \n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate_constructor(t *testing.T) {
+	cl := &element.Class{
+		Modi: "class",
+		Cn:   "Point",
+		Cvds: []*element.ClassVarDec{
+			{Modi: "field", Vt: element.NewKeyword("int"), Vn: "x", Vns: []*element.NextVns{{Comma: ",", Vn: "y"}}},
+			{Modi: "static", Vt: element.NewKeyword("int"), Vn: "count"},
+		},
+		Sds: []*element.SubroutineDec{
+			{
+				Modi: "constructor",
+				St:   element.NewIdentifier("Point"),
+				Sn:   "new",
+				Pl: &element.ParameterList{
+					Type: element.NewKeyword("int"),
+					Vn:   "Ax",
+				},
+				Sb: element.SubroutineBody{
+					Stmts: []element.Statement{
+						&element.LetStatement{Vn: "x", Rexp: element.Expression{Term: &element.VarName{V: "Ax"}}},
+						&element.ReturnStatement{Exp: &element.Expression{Term: &element.KeywordConstant{V: "this"}}},
+					},
+				},
+			},
+		},
+	}
+	want := `function Point.new 0
+push constant 2
+call Memory.alloc 1
+pop pointer 0
+push argument 0
+pop this 0
+push pointer 0
+return
+`
+	var b bytes.Buffer
+	if err := Generate(&b, cl); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("Generate() got = \n%v, want = \n%v", got, want)
+	}
+}
+
+func TestGenerate_error(t *testing.T) {
+	s := "class Main { function void main() { let x = 1; return; } }"
+	wantErr := "undefined variable 'x' in class Main"
+	err := Generate(&bytes.Buffer{}, parse(t, s))
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("Generate() error got = %v, want = %v", err, wantErr)
+	}
+}
@@ -0,0 +1,202 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context is the number of the unchanged lines around a change in a hunk.
+const context = 3
+
+// opKind is the kind of op.
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op is an edit of a line.
+// ai and bi are the indexes of the line in a and b.
+type op struct {
+	kind   opKind
+	ai, bi int
+}
+
+// Unified returns the unified diff of a and b with 3 lines of context,
+// or nil if they are equal.
+func Unified(aName, bName string, a, b []byte) []byte {
+	al, bl := splitLines(a), splitLines(b)
+	ops := edits(al, bl)
+	hunks := group(ops)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+	for _, h := range hunks {
+		as, an, bs, bn := h[0].ai, 0, h[0].bi, 0
+		for _, o := range h {
+			if o.kind != opInsert {
+				an++
+			}
+			if o.kind != opDelete {
+				bn++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(as, an), hunkRange(bs, bn))
+		for _, o := range h {
+			line := ""
+			switch o.kind {
+			case opInsert:
+				line = bl[o.bi]
+			default:
+				line = al[o.ai]
+			}
+			buf.WriteByte(byte(o.kind))
+			buf.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// hunkRange returns "start,count" with the 1-based start.
+// The start of an empty range is the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s after each newline.
+func splitLines(s []byte) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(s), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits returns the shortest edit script from a to b by the Myers algorithm.
+func edits(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // down: insert
+			} else {
+				x = v[offset+k-1] + 1 // right: delete
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, offset, n, m, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack follows trace from (n, m) back to (0, 0).
+// trace[d] is v before the step d.
+func backtrack(trace [][]int, offset, n, m, d int) []op {
+	var ops []op
+	x, y := n, m
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[offset+pk]
+		py := px - pk
+		for x > px && y > py {
+			x--
+			y--
+			ops = append(ops, op{opEqual, x, y})
+		}
+		if pk == k+1 {
+			ops = append(ops, op{opInsert, px, py})
+		} else {
+			ops = append(ops, op{opDelete, px, py})
+		}
+		x, y = px, py
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{opEqual, x, y})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// group splits ops into the hunks of the changes with context lines.
+func group(ops []op) [][]op {
+	var hunks [][]op
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// extend while the next change is within 2*context equal lines
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == opEqual {
+				j++
+			}
+			if j == len(ops) || j-end > 2*context {
+				end += min(context, j-end)
+				break
+			}
+			end = j
+		}
+		hunks = append(hunks, ops[start:end])
+		i = end
+	}
+	return hunks
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
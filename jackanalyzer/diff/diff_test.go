@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			`--- a
++++ b
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			"insert into empty",
+			"",
+			"a\n",
+			`--- a
++++ b
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			"two hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			`--- a
++++ b
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,3 @@
+ 9
+ 10
+ 11
+-12
+`,
+		},
+		{
+			"merged hunks",
+			"1\n2\n3\n4\n5\n",
+			"1\nx\n3\n4\ny\n",
+			`--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++x
+ 3
+ 4
+-5
++y
+`,
+		},
+		{
+			"no newline at end",
+			"a\n",
+			"a",
+			`--- a
++++ b
+@@ -1 +1 @@
+-a
++a
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Unified("a", "b", []byte(tt.a), []byte(tt.b)))
+			if got != tt.want {
+				t.Errorf("Unified() = \n%s\nwant \n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified_apply(t *testing.T) {
+	// the edit script must turn a into b
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	var got []string
+	for _, o := range edits(a, b) {
+		switch o.kind {
+		case opEqual:
+			if a[o.ai] != b[o.bi] {
+				t.Fatalf("equal op %+v on %v and %v", o, a[o.ai], b[o.bi])
+			}
+			got = append(got, a[o.ai])
+		case opInsert:
+			got = append(got, b[o.bi])
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(b, " ") {
+		t.Errorf("edits() produce %v, want %v", got, b)
+	}
+}
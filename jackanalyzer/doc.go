@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"jackanalyzer/doc"
+	"jackanalyzer/element"
+	"os"
+	"path/filepath"
+)
+
+// runDoc is the entry point of 'jackanalyzer doc'. It returns the exit code.
+func runDoc(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jackanalyzer doc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	var out string
+	var markdown, withOS bool
+	fs.StringVar(&out, "o", "doc", "the output directory")
+	fs.BoolVar(&markdown, "markdown", false, "write Markdown instead of HTML")
+	fs.BoolVar(&withOS, "os", false, "document the Jack OS classes too")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	files, err := jackFiles(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	p, err := loadProgram(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	code := 0
+	var classes []*element.Class
+	for _, path := range files {
+		f, ok := p.File(filepath.Clean(path))
+		if !ok {
+			continue
+		}
+		if f.Err != nil {
+			code = 1
+			fmt.Fprintln(stderr, errorMessage(f.Path, f.Err))
+			continue
+		}
+		classes = append(classes, f.Class)
+	}
+	if withOS {
+		classes = append(classes, p.OS...)
+	}
+
+	format := doc.HTML
+	if markdown {
+		format = doc.Markdown
+	}
+	if err := writePages(out, doc.Generate(classes, format)); err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// writePages writes pages into the directory dir, creating it if needed.
+func writePages(dir string, pages []doc.Page) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, pg := range pages {
+		if err := ioutil.WriteFile(filepath.Join(dir, pg.Name), pg.Content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package doc
+
+import (
+	"html"
+	"jackanalyzer/element"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format is the output format of Generate.
+type Format int
+
+const (
+	HTML Format = iota
+	Markdown
+)
+
+// Ext returns the file extension of the pages of f.
+func (f Format) Ext() string {
+	if f == Markdown {
+		return ".md"
+	}
+	return ".html"
+}
+
+// Page is a generated file.
+type Page struct {
+	Name    string // file name, e.g. 'index.html' or 'Math.html'
+	Content []byte
+}
+
+// Generate returns the reference of classes: the index page listing the classes
+// and a page per class with the doc comments of its variables and subroutines.
+// The types of the other classes in the signatures link to their pages,
+// and each subroutine has the anchor of its name, e.g. 'Math.html#abs'.
+// The index comes first, then the classes in the order of the names.
+func Generate(classes []*element.Class, f Format) []Page {
+	cls := append([]*element.Class(nil), classes...)
+	sort.SliceStable(cls, func(i, j int) bool {
+		return cls[i].Cn < cls[j].Cn
+	})
+	g := &generator{format: f, classes: map[string]bool{}}
+	for _, cl := range cls {
+		g.classes[string(cl.Cn)] = true
+	}
+
+	pages := []Page{{Name: "index" + f.Ext(), Content: g.index(cls)}}
+	for _, cl := range cls {
+		pages = append(pages, Page{Name: string(cl.Cn) + f.Ext(), Content: g.class(cl)})
+	}
+	return pages
+}
+
+// Text returns the text of the doc comment without '/**', '*/'
+// and the leading '*' of the lines.
+func Text(doc string) string {
+	doc = strings.TrimSuffix(strings.TrimPrefix(doc, "/**"), "*/")
+	lines := strings.Split(doc, "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "*") {
+			l = strings.TrimPrefix(l[1:], " ")
+		}
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Summary returns the first sentence of the doc comment.
+func Summary(doc string) string {
+	text := Text(doc)
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+var mdEscaper = strings.NewReplacer("_", `\_`, "*", `\*`)
+
+// generator renders the pages of a set of classes.
+type generator struct {
+	format  Format
+	classes map[string]bool // the classes with a page
+}
+
+func (g *generator) index(cls []*element.Class) []byte {
+	var b strings.Builder
+	if g.format == Markdown {
+		b.WriteString("# API reference\n\n| Class | Description |\n| --- | --- |\n")
+		for _, cl := range cls {
+			cn := string(cl.Cn)
+			b.WriteString("| [" + mdEscaper.Replace(cn) + "](" + cn + ".md) | " + strings.Replace(Summary(cl.Doc), "|", `\|`, -1) + " |\n")
+		}
+		return []byte(b.String())
+	}
+	g.header(&b, "API reference")
+	b.WriteString("<h1>API reference</h1>\n<table>\n<tr><th>Class</th><th>Description</th></tr>\n")
+	for _, cl := range cls {
+		cn := html.EscapeString(string(cl.Cn))
+		b.WriteString(`<tr><td><a href="` + cn + `.html">` + cn + "</a></td><td>" + html.EscapeString(Summary(cl.Doc)) + "</td></tr>\n")
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+func (g *generator) class(cl *element.Class) []byte {
+	var b strings.Builder
+	cn := string(cl.Cn)
+	if g.format == HTML {
+		g.header(&b, cn)
+		b.WriteString(`<p><a href="index.html">Index</a></p>` + "\n")
+	} else {
+		b.WriteString("[Index](index.md)\n\n")
+	}
+	g.heading(&b, 1, "", g.code(g.esc("class "+cn)))
+	g.text(&b, cl.Doc)
+
+	if len(cl.Cvds) > 0 {
+		g.heading(&b, 2, "", "Variables")
+		for _, cvd := range cl.Cvds {
+			names := []string{string(cvd.Vn)}
+			for _, v := range cvd.Vns {
+				names = append(names, string(v.Vn))
+			}
+			g.heading(&b, 3, "", g.code(g.esc(string(cvd.Modi)+" ")+g.typ(element.TypeName(cvd.Vt))+g.esc(" "+strings.Join(names, ", "))))
+			g.text(&b, cvd.Doc)
+		}
+	}
+	if len(cl.Sds) > 0 {
+		g.heading(&b, 2, "", "Subroutines")
+		for _, sd := range cl.Sds {
+			g.heading(&b, 3, string(sd.Sn), g.signature(sd))
+			g.text(&b, sd.Doc)
+		}
+	}
+	if g.format == HTML {
+		b.WriteString("</body>\n</html>\n")
+	}
+	return []byte(b.String())
+}
+
+// signature returns 'kind type name(type name, ...)' with the types linked.
+func (g *generator) signature(sd *element.SubroutineDec) string {
+	s := g.esc(string(sd.Modi)+" ") + g.typ(element.TypeName(sd.St)) + g.esc(" "+string(sd.Sn)+"(")
+	if sd.Pl != nil {
+		s += g.typ(element.TypeName(sd.Pl.Type)) + g.esc(" "+string(sd.Pl.Vn))
+		for _, v := range sd.Pl.Next {
+			s += g.esc(", ") + g.typ(element.TypeName(v.Type)) + g.esc(" "+string(v.Vn))
+		}
+	}
+	return g.code(s + g.esc(")"))
+}
+
+// typ returns the type name linked to its page if it has one.
+func (g *generator) typ(name string) string {
+	if !g.classes[name] {
+		return g.esc(name)
+	}
+	if g.format == Markdown {
+		return "[" + mdEscaper.Replace(name) + "](" + name + ".md)"
+	}
+	return `<a href="` + html.EscapeString(name) + `.html">` + html.EscapeString(name) + "</a>"
+}
+
+// esc escapes s in a signature.
+// The signatures of Markdown are plain text, so only '_' and '*' are escaped.
+func (g *generator) esc(s string) string {
+	if g.format == Markdown {
+		return mdEscaper.Replace(s)
+	}
+	return html.EscapeString(s)
+}
+
+// code returns the escaped signature s as code.
+func (g *generator) code(s string) string {
+	if g.format == Markdown {
+		return s
+	}
+	return "<code>" + s + "</code>"
+}
+
+// heading writes the heading of level with the anchor id if not empty.
+// s is already rendered.
+func (g *generator) heading(b *strings.Builder, level int, id, s string) {
+	if g.format == Markdown {
+		if id != "" {
+			b.WriteString(`<a id="` + id + `"></a>` + "\n\n")
+		}
+		b.WriteString(strings.Repeat("#", level) + " " + s + "\n\n")
+		return
+	}
+	h := "h" + strconv.Itoa(level)
+	b.WriteString("<" + h)
+	if id != "" {
+		b.WriteString(` id="` + html.EscapeString(id) + `"`)
+	}
+	b.WriteString(">" + s + "</" + h + ">\n")
+}
+
+// text writes the paragraphs of the doc comment.
+// The text is Markdown as is, or escaped for HTML.
+func (g *generator) text(b *strings.Builder, doc string) {
+	text := Text(doc)
+	if text == "" {
+		return
+	}
+	if g.format == Markdown {
+		b.WriteString(text + "\n\n")
+		return
+	}
+	for _, p := range strings.Split(text, "\n\n") {
+		b.WriteString("<p>" + html.EscapeString(strings.TrimSpace(p)) + "</p>\n")
+	}
+}
+
+func (g *generator) header(b *strings.Builder, title string) {
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) + "</title>\n</head>\n<body>\n")
+}
@@ -0,0 +1,131 @@
+package doc
+
+import (
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"one line", "/** Returns x. */", "Returns x."},
+		{"stars", "/**\n * Returns x.\n *\n *   indented\n */", "Returns x.\n\n  indented"},
+		{"no stars", "/** Returns\n    x. */", "Returns\nx."},
+		{"crlf", "/**\r\n * a\r\n */", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.doc); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"sentence", "/** Returns x. Never fails. */", "Returns x."},
+		{"lines", "/**\n * Returns\n * x\n *\n * More.\n */", "Returns x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summary(tt.doc); got != tt.want {
+				t.Errorf("Summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	classes := []*element.Class{
+		parse(t, `/** A point. */
+class Point {
+    /** The coordinates. */
+    field int x, y;
+    /** Creates a point. */
+    constructor Point new(int ax, int ay) { return this; }
+    method int dist(Point other, String my_name) { return 0; }
+}`),
+		parse(t, `class Main { function void main() { return; } }`),
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		want   []string // the names and the contents of the pages
+	}{
+		{
+			"markdown",
+			Markdown,
+			[]string{
+				"index.md", "# API reference\n\n| Class | Description |\n| --- | --- |\n" +
+					"| [Main](Main.md) |  |\n| [Point](Point.md) | A point. |\n",
+				"Main.md", "[Index](index.md)\n\n# class Main\n\n## Subroutines\n\n" +
+					"<a id=\"main\"></a>\n\n### function void main()\n\n",
+				"Point.md", "[Index](index.md)\n\n# class Point\n\nA point.\n\n" +
+					"## Variables\n\n### field int x, y\n\nThe coordinates.\n\n" +
+					"## Subroutines\n\n" +
+					"<a id=\"new\"></a>\n\n### constructor [Point](Point.md) new(int ax, int ay)\n\nCreates a point.\n\n" +
+					"<a id=\"dist\"></a>\n\n### method int dist([Point](Point.md) other, String my\\_name)\n\n",
+			},
+		},
+		{
+			"html",
+			HTML,
+			[]string{
+				"index.html", "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>API reference</title>\n</head>\n<body>\n" +
+					"<h1>API reference</h1>\n<table>\n<tr><th>Class</th><th>Description</th></tr>\n" +
+					"<tr><td><a href=\"Main.html\">Main</a></td><td></td></tr>\n" +
+					"<tr><td><a href=\"Point.html\">Point</a></td><td>A point.</td></tr>\n" +
+					"</table>\n</body>\n</html>\n",
+				"Main.html", "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Main</title>\n</head>\n<body>\n" +
+					"<p><a href=\"index.html\">Index</a></p>\n<h1><code>class Main</code></h1>\n" +
+					"<h2>Subroutines</h2>\n<h3 id=\"main\"><code>function void main()</code></h3>\n</body>\n</html>\n",
+				"Point.html", "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Point</title>\n</head>\n<body>\n" +
+					"<p><a href=\"index.html\">Index</a></p>\n<h1><code>class Point</code></h1>\n<p>A point.</p>\n" +
+					"<h2>Variables</h2>\n<h3><code>field int x, y</code></h3>\n<p>The coordinates.</p>\n" +
+					"<h2>Subroutines</h2>\n" +
+					"<h3 id=\"new\"><code>constructor <a href=\"Point.html\">Point</a> new(int ax, int ay)</code></h3>\n<p>Creates a point.</p>\n" +
+					"<h3 id=\"dist\"><code>method int dist(<a href=\"Point.html\">Point</a> other, String my_name)</code></h3>\n" +
+					"</body>\n</html>\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range Generate(classes, tt.format) {
+				got = append(got, p.Name, string(p.Content))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Generate() = %d pages, want %d", len(got)/2, len(tt.want)/2)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Generate()[%d] = \n%s\nwant \n%s", i/2, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func parse(t *testing.T, src string) *element.Class {
+	t.Helper()
+	cl, err := cmplengn.ParseScanner(tokenizer.NewScanner(strings.NewReader(src), tokenizer.WithTrivia()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
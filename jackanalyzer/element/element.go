@@ -2,6 +2,7 @@ package element
 
 import (
 	"encoding/xml"
+	"jackanalyzer/token"
 	"strconv"
 )
 
@@ -42,6 +43,10 @@ type Class struct {
 	Cvds   []*ClassVarDec   // classVarDec*
 	Sds    []*SubroutineDec // subroutineDec*
 	RBrace symbol           // '}'
+
+	CnPos     token.Pos // position of Cn
+	RBracePos token.Pos // position of RBrace
+	Doc       string    // the '/** */' comment before 'class', set with the trivia tokens
 }
 
 // ClassVarDec represent to classVarDec.
@@ -49,10 +54,13 @@ type Class struct {
 //  ( 'static' | 'field' ) type varName (',' varName)* ';'
 type ClassVarDec struct {
 	Modi keyword    // 'static' | 'field'
-	Vt   keyword    // type
+	Vt   Types      // type
 	Vn   identifier // varName
 	Vns  []*NextVns // (',' varName)*
 	Sc   symbol     // ';'
+
+	VnPos token.Pos // position of Vn
+	Doc   string    // the '/** */' comment before Modi, set with the trivia tokens
 }
 
 // NextVns is Next varNames.
@@ -61,6 +69,8 @@ type ClassVarDec struct {
 type NextVns struct {
 	Comma symbol
 	Vn    identifier
+
+	VnPos token.Pos // position of Vn
 }
 
 // SubroutineDec represent to subroutineDec.
@@ -70,12 +80,15 @@ type NextVns struct {
 //  subroutineBody
 type SubroutineDec struct {
 	Modi keyword        // 'constructor' | 'function' | 'method'
-	St   keyword        // 'void' | type
+	St   Types          // 'void' | type
 	Sn   identifier     // subroutineName
 	LP   symbol         // '('
 	Pl   *ParameterList // parameterList
 	RP   symbol         // ')'
 	Sb   SubroutineBody // subroutineBody
+
+	SnPos token.Pos // position of Sn
+	Doc   string    // the '/** */' comment before Modi, set with the trivia tokens
 }
 
 // ParameterList represent to parameterList.
@@ -85,6 +98,8 @@ type ParameterList struct {
 	Type Types
 	Vn   identifier
 	Next []*NextParam
+
+	VnPos token.Pos // position of Vn
 }
 
 // NextParam is the second and subsequent elements of ParameterList.
@@ -92,6 +107,8 @@ type NextParam struct {
 	Comma symbol
 	Type  Types
 	Vn    identifier
+
+	VnPos token.Pos // position of Vn
 }
 
 // SubroutineBody represent to subroutineBody.
@@ -102,6 +119,8 @@ type SubroutineBody struct {
 	Vd    []*VarDec   // varDec*
 	Stmts []Statement // statements
 	RB    symbol      // '}'
+
+	RBPos token.Pos // position of RB
 }
 
 // VarDec represent to varDec.
@@ -113,11 +132,15 @@ type VarDec struct {
 	Vn   identifier // varName
 	Vns  []*NextVns // (',' varName)*
 	Sc   symbol     // ';'
+
+	VnPos token.Pos // position of Vn
 }
 
 // Types represent to type.
 //
 //  keyword ('int' | 'char' | 'boolean') | identifier (className)
+//
+// SubroutineDec.St is also keyword 'void'.
 type Types interface {
 	types()
 }
@@ -125,6 +148,19 @@ type Types interface {
 func (k keyword) types()    {}
 func (i identifier) types() {}
 
+// TypeName returns the name of the type.
+//
+//  'int' | 'char' | 'boolean' | className
+func TypeName(t Types) string {
+	switch v := t.(type) {
+	case keyword:
+		return string(v)
+	case identifier:
+		return string(v)
+	}
+	return ""
+}
+
 /*
 Statement
 */
@@ -147,6 +183,9 @@ type LetStatement struct {
 	Eq   symbol      // '='
 	Rexp Expression  // expression
 	Sc   symbol      // ';'
+
+	Pos   token.Pos // position of Modi
+	VnPos token.Pos // position of Vn
 }
 
 func (ls *LetStatement) statement() {}
@@ -167,6 +206,10 @@ type IfStatement struct {
 	ELB    symbol      // '{'
 	EStmts []Statement // statements
 	ERB    symbol      // '}'
+
+	Pos    token.Pos // position of Modi
+	RBPos  token.Pos // position of RB
+	ERBPos token.Pos // position of ERB
 }
 
 func (is *IfStatement) statement() {}
@@ -182,6 +225,9 @@ type WhileStatement struct {
 	LB    symbol      // '{'
 	Stmts []Statement // statements
 	RB    symbol      // '}'
+
+	Pos   token.Pos // position of Modi
+	RBPos token.Pos // position of RB
 }
 
 func (ws *WhileStatement) statement() {}
@@ -193,6 +239,8 @@ type DoStatement struct {
 	Modi keyword         // 'do'
 	Sub  *SubroutineCall // subroutineCall
 	Sc   symbol          // ';'
+
+	Pos token.Pos // position of Modi
 }
 
 func (do *DoStatement) statement() {}
@@ -204,6 +252,8 @@ type ReturnStatement struct {
 	Modi keyword     // 'return'
 	Exp  *Expression // expression?
 	Sc   symbol      // ';'
+
+	Pos token.Pos // position of Modi
 }
 
 func (rs *ReturnStatement) statement() {}
@@ -222,6 +272,8 @@ type Expression struct {
 type BopTerm struct {
 	Bop  symbol // binary operator
 	Term Term
+
+	BopPos token.Pos // position of Bop
 }
 
 // Term is term
@@ -251,6 +303,8 @@ type KeywordConstant struct {
 //  varName
 type VarName struct {
 	V identifier
+
+	Pos token.Pos // position of V
 }
 
 // CallIndex is Term.
@@ -261,6 +315,8 @@ type CallIndex struct {
 	LB  symbol
 	Exp Expression
 	RB  symbol
+
+	VnPos token.Pos // position of Vn
 }
 
 // SubroutineCall is Term.
@@ -274,6 +330,8 @@ type SubroutineCall struct {
 	LP   symbol       // '('
 	ExpL []Expression // (expression(, expression)*)?
 	RP   symbol       // ')'
+
+	Pos token.Pos // position of Name, or Sn if Name is empty
 }
 
 // Args is Term.
@@ -291,6 +349,8 @@ type Args struct {
 type UopTerm struct {
 	Uop  symbol // unary operator
 	Term Term
+
+	UopPos token.Pos // position of Uop
 }
 
 func (ic *IntegerConstant) term() {}
@@ -302,6 +362,35 @@ func (sbc *SubroutineCall) term() {}
 func (args *Args) term()          {}
 func (ut *UopTerm) term()         {}
 
+/*
+Constructor
+*/
+
+// NewKeyword returns keyword for building element outside of this package.
+func NewKeyword(s string) keyword {
+	return keyword(s)
+}
+
+// NewIdentifier returns identifier for building element outside of this package.
+func NewIdentifier(s string) identifier {
+	return identifier(s)
+}
+
+// NewSymbol returns symbol for building element outside of this package.
+func NewSymbol(s string) symbol {
+	return symbol(s)
+}
+
+// NewIntegerConstant returns IntegerConstant term.
+func NewIntegerConstant(v int) *IntegerConstant {
+	return &IntegerConstant{V: integerConstant(v)}
+}
+
+// NewStringConstant returns StringConstant term.
+func NewStringConstant(s string) *StringConstant {
+	return &StringConstant{V: stringConstant(s)}
+}
+
 // generate Element for *xml.EncodeElement.
 func genElement(s interface{}) (string, xml.StartElement) {
 	var c string // contents
@@ -336,12 +425,16 @@ func (cl Class) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	e.EncodeElement(genElement(cl.LBrace))
 
 	// ClassVarDec
-	if len(cl.Cvds) != 0 {
-		for _, v := range cl.Cvds {
-			v.genClassVarDec(e)
-		}
+	for _, v := range cl.Cvds {
+		v.genClassVarDec(e)
 	}
 
+	// SubroutineDec
+	for _, v := range cl.Sds {
+		v.genSubroutineDec(e)
+	}
+
+	e.EncodeElement(genElement(cl.RBrace))
 	e.EncodeToken(start.End())
 	return nil
 }
@@ -472,12 +565,15 @@ func (is *IfStatement) genIfStatement(e *xml.Encoder) {
 	e.EncodeElement(genElement(is.RB))
 
 	// ( 'else' '{' statements '}' )?
-	if is.Else != "" && is.ELB != "" && len(is.EStmts) > 0 && is.ERB != "" {
+	if is.Else != "" {
 		e.EncodeElement(genElement(is.Else))
 		e.EncodeElement(genElement(is.ELB))
+		es := xml.StartElement{Name: xml.Name{Local: "statements"}}
+		e.EncodeToken(es)
 		for _, v := range is.EStmts {
 			genStatement(v, e)
 		}
+		e.EncodeToken(es.End())
 		e.EncodeElement(genElement(is.ERB))
 	}
 
@@ -589,7 +685,10 @@ func (sbc *SubroutineCall) genSubroutineCall(e *xml.Encoder) {
 	e.EncodeElement(genElement(sbc.LP))
 	start := xml.StartElement{Name: xml.Name{Local: "expressionList"}}
 	e.EncodeToken(start)
-	for _, v := range sbc.ExpL {
+	for i, v := range sbc.ExpL {
+		if i > 0 {
+			e.EncodeElement(genElement(symbol(",")))
+		}
 		v.genExpression(e)
 	}
 	e.EncodeToken(start.End())
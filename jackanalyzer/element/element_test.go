@@ -98,12 +98,14 @@ func Test_class_MarshalXML(t *testing.T) {
 				Modi:   "class",
 				Cn:     "Main",
 				LBrace: "{",
+				RBrace: "}",
 			},
 			`
 <class>
   <keyword> class </keyword>
   <identifier> Main </identifier>
   <symbol> { </symbol>
+  <symbol> } </symbol>
 </class>
 `,
 		},
@@ -116,7 +118,7 @@ func Test_class_MarshalXML(t *testing.T) {
 				Cvds: []*ClassVarDec{
 					{
 						Modi: "field",
-						Vt:   "int",
+						Vt:   keyword("int"),
 						Vn:   "x",
 						Vns: []*NextVns{
 							{
@@ -127,6 +129,7 @@ func Test_class_MarshalXML(t *testing.T) {
 						Sc: ";",
 					},
 				},
+				RBrace: "}",
 			},
 			`
 <class>
@@ -141,6 +144,7 @@ func Test_class_MarshalXML(t *testing.T) {
     <identifier> y </identifier>
     <symbol> ; </symbol>
   </classVarDec>
+  <symbol> } </symbol>
 </class>
 `,
 		},
@@ -153,7 +157,7 @@ func Test_class_MarshalXML(t *testing.T) {
 				Cvds: []*ClassVarDec{
 					{
 						Modi: "field",
-						Vt:   "int",
+						Vt:   keyword("int"),
 						Vn:   "x",
 						Vns: []*NextVns{
 							{
@@ -165,11 +169,12 @@ func Test_class_MarshalXML(t *testing.T) {
 					},
 					{
 						Modi: "field",
-						Vt:   "int",
+						Vt:   keyword("int"),
 						Vn:   "size",
 						Sc:   ";",
 					},
 				},
+				RBrace: "}",
 			},
 			`
 <class>
@@ -190,6 +195,61 @@ func Test_class_MarshalXML(t *testing.T) {
     <identifier> size </identifier>
     <symbol> ; </symbol>
   </classVarDec>
+  <symbol> } </symbol>
+</class>
+`,
+		},
+		{
+			"subroutineDec",
+			Class{
+				Modi:   "class",
+				Cn:     "Main",
+				LBrace: "{",
+				Sds: []*SubroutineDec{
+					{
+						Modi: "function",
+						St:   keyword("void"),
+						Sn:   "main",
+						LP:   "(",
+						RP:   ")",
+						Sb: SubroutineBody{
+							LB: "{",
+							Stmts: []Statement{
+								&ReturnStatement{
+									Modi: "return",
+									Sc:   ";",
+								},
+							},
+							RB: "}",
+						},
+					},
+				},
+				RBrace: "}",
+			},
+			`
+<class>
+  <keyword> class </keyword>
+  <identifier> Main </identifier>
+  <symbol> { </symbol>
+  <subroutineDec>
+    <keyword> function </keyword>
+    <keyword> void </keyword>
+    <identifier> main </identifier>
+    <symbol> ( </symbol>
+    <parameterList></parameterList>
+    <symbol> ) </symbol>
+    <subroutineBody>
+      <symbol> { </symbol>
+      <statements>
+        <returnStatement>
+          <keyword> return </keyword>
+          <symbol> ; </symbol>
+        </returnStatement>
+      </statements>
+      <symbol> } </symbol>
+    </subroutineBody>
+  </subroutineDec>
+  <symbol> } </symbol>
 </class>
 `,
 		},
@@ -217,7 +277,7 @@ func TestClassVarDec_genClassVarDec(t *testing.T) {
 			"test",
 			&ClassVarDec{
 				Modi: "field",
-				Vt:   "int",
+				Vt:   keyword("int"),
 				Vn:   "x",
 				Vns: []*NextVns{
 					{
@@ -236,6 +296,23 @@ func TestClassVarDec_genClassVarDec(t *testing.T) {
   <identifier> y </identifier>
   <symbol> ; </symbol>
 </classVarDec>
+`,
+		},
+		{
+			"test class type",
+			&ClassVarDec{
+				Modi: "field",
+				Vt:   identifier("Square"),
+				Vn:   "square",
+				Sc:   ";",
+			},
+			`
+<classVarDec>
+  <keyword> field </keyword>
+  <identifier> Square </identifier>
+  <identifier> square </identifier>
+  <symbol> ; </symbol>
+</classVarDec>
 `,
 		},
 	}
@@ -809,6 +886,44 @@ func TestSubroutineCall_genSubroutineCall(t *testing.T) {
   </expression>
 </expressionList>
 <symbol> ) </symbol>
+`,
+		},
+		{
+			"testing when ExpL has multiple expressions.",
+			&SubroutineCall{
+				Sn: "main",
+				LP: "(",
+				ExpL: []Expression{
+					{
+						Term: &VarName{
+							V: "i",
+						},
+					},
+					{
+						Term: &VarName{
+							V: "j",
+						},
+					},
+				},
+				RP: ")",
+			},
+			`
+<identifier> main </identifier>
+<symbol> ( </symbol>
+<expressionList>
+  <expression>
+    <term>
+      <identifier> i </identifier>
+    </term>
+  </expression>
+  <symbol> , </symbol>
+  <expression>
+    <term>
+      <identifier> j </identifier>
+    </term>
+  </expression>
+</expressionList>
+<symbol> ) </symbol>
 `,
 		},
 	}
@@ -1294,6 +1409,91 @@ func TestIfStatement_genIfStatement(t *testing.T) {
   </statements>
   <symbol> } </symbol>
 </ifStatement>
+`,
+		},
+		{
+			"test if (i) {} else { return; }",
+			&IfStatement{
+				Modi: "if",
+				LP:   "(",
+				LExp: Expression{
+					Term: &VarName{
+						V: "i",
+					},
+				},
+				RP:   ")",
+				LB:   "{",
+				RB:   "}",
+				Else: "else",
+				ELB:  "{",
+				EStmts: []Statement{
+					&ReturnStatement{
+						Modi: "return",
+						Sc:   ";",
+					},
+				},
+				ERB: "}",
+			},
+			`
+<ifStatement>
+  <keyword> if </keyword>
+  <symbol> ( </symbol>
+  <expression>
+    <term>
+      <identifier> i </identifier>
+    </term>
+  </expression>
+  <symbol> ) </symbol>
+  <symbol> { </symbol>
+  <statements></statements>
+  <symbol> } </symbol>
+  <keyword> else </keyword>
+  <symbol> { </symbol>
+  <statements>
+    <returnStatement>
+      <keyword> return </keyword>
+      <symbol> ; </symbol>
+    </returnStatement>
+  </statements>
+  <symbol> } </symbol>
+</ifStatement>
+`,
+		},
+		{
+			"test empty else",
+			&IfStatement{
+				Modi: "if",
+				LP:   "(",
+				LExp: Expression{
+					Term: &VarName{
+						V: "i",
+					},
+				},
+				RP:   ")",
+				LB:   "{",
+				RB:   "}",
+				Else: "else",
+				ELB:  "{",
+				ERB:  "}",
+			},
+			`
+<ifStatement>
+  <keyword> if </keyword>
+  <symbol> ( </symbol>
+  <expression>
+    <term>
+      <identifier> i </identifier>
+    </term>
+  </expression>
+  <symbol> ) </symbol>
+  <symbol> { </symbol>
+  <statements></statements>
+  <symbol> } </symbol>
+  <keyword> else </keyword>
+  <symbol> { </symbol>
+  <statements></statements>
+  <symbol> } </symbol>
+</ifStatement>
 `,
 		},
 	}
@@ -1651,7 +1851,7 @@ func TestSubroutineDec_genSubroutineDec(t *testing.T) {
 			}`,
 			&SubroutineDec{
 				Modi: "function",
-				St:   "void",
+				St:   keyword("void"),
 				Sn:   "main",
 				LP:   "(",
 				RP:   ")",
@@ -1780,3 +1980,40 @@ func TestSubroutineDec_genSubroutineDec(t *testing.T) {
 		})
 	}
 }
+
+func TestConstructors(t *testing.T) {
+	if got := NewKeyword("class"); got != keyword("class") {
+		t.Errorf("NewKeyword() = %#v", got)
+	}
+	if got := NewIdentifier("hoge"); got != identifier("hoge") {
+		t.Errorf("NewIdentifier() = %#v", got)
+	}
+	if got := NewSymbol("{"); got != symbol("{") {
+		t.Errorf("NewSymbol() = %#v", got)
+	}
+	if got := NewIntegerConstant(123); !reflect.DeepEqual(got, &IntegerConstant{V: 123}) {
+		t.Errorf("NewIntegerConstant() = %#v", got)
+	}
+	if got := NewStringConstant("hoge"); !reflect.DeepEqual(got, &StringConstant{V: "hoge"}) {
+		t.Errorf("NewStringConstant() = %#v", got)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		name string
+		t    Types
+		want string
+	}{
+		{"keyword", keyword("int"), "int"},
+		{"identifier", identifier("Square"), "Square"},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TypeName(tt.t); got != tt.want {
+				t.Errorf("TypeName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
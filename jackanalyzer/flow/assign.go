@@ -0,0 +1,234 @@
+package flow
+
+import (
+	"fmt"
+	"jackanalyzer/check"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"sort"
+)
+
+// assigned is the set of the local variables definitely assigned.
+type assigned map[string]bool
+
+// ref is a variable read in a statement.
+type ref struct {
+	name string
+	pos  token.Pos
+}
+
+// CheckAssignments reports the local variables which may be read before assigned
+// on a path of Graph, and the local variables which are assigned but never read, as WARNING.
+// A local is assigned only by 'let varName = expression';
+// 'let varName[i] = expression' reads the array reference.
+func CheckAssignments(cl *element.Class) check.Diagnostics {
+	var diags check.Diagnostics
+	for _, sd := range cl.Sds {
+		diags = append(diags, checkAssignments(sd)...)
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	})
+	return diags
+}
+
+func checkAssignments(sd *element.SubroutineDec) check.Diagnostics {
+	locals := map[string]token.Pos{}
+	for _, vd := range sd.Sb.Vd {
+		locals[string(vd.Vn)] = vd.VnPos
+		for _, v := range vd.Vns {
+			locals[string(v.Vn)] = v.VnPos
+		}
+	}
+	if len(locals) == 0 {
+		return nil
+	}
+
+	g := Build(sd)
+	in := assignedIn(g, locals)
+
+	var diags check.Diagnostics
+	reported := map[string]bool{}
+	read, written := map[string]bool{}, map[string]bool{}
+	for _, blk := range g.Blocks {
+		cur := assigned{}
+		for name := range in[blk.Index] {
+			cur[name] = true
+		}
+		for _, stmt := range blk.Stmts {
+			for _, r := range reads(stmt) {
+				if _, ok := locals[r.name]; !ok {
+					continue
+				}
+				read[r.name] = true
+				if blk.Live && !cur[r.name] && !reported[r.name] {
+					reported[r.name] = true
+					diags = append(diags, check.Diagnostic{
+						Pos:      r.pos,
+						Severity: check.WARNING,
+						Msg:      fmt.Sprintf("local variable '%s' may be used before assignment", r.name),
+					})
+				}
+			}
+			if name, ok := write(stmt); ok {
+				if _, ok := locals[name]; ok {
+					written[name] = true
+					cur[name] = true
+				}
+			}
+		}
+	}
+	for name := range written {
+		if !read[name] {
+			diags = append(diags, check.Diagnostic{
+				Pos:      locals[name],
+				Severity: check.WARNING,
+				Msg:      fmt.Sprintf("local variable '%s' is assigned but never used", name),
+			})
+		}
+	}
+	return diags
+}
+
+// assignedIn returns the locals definitely assigned at the entry of each live Block:
+// the intersection over the live predecessors, iterated to the fixed point.
+func assignedIn(g *Graph, locals map[string]token.Pos) []assigned {
+	in := make([]assigned, len(g.Blocks))
+	out := make([]assigned, len(g.Blocks))
+	for changed := true; changed; {
+		changed = false
+		for _, blk := range g.Blocks {
+			if !blk.Live {
+				continue
+			}
+			var s assigned
+			if blk == g.Entry {
+				s = assigned{}
+			}
+			for _, p := range blk.Preds {
+				if !p.Live || out[p.Index] == nil {
+					continue // not computed yet, the top of the lattice
+				}
+				s = intersect(s, out[p.Index])
+			}
+			if s == nil {
+				continue
+			}
+			in[blk.Index] = s
+			o := assigned{}
+			for name := range s {
+				o[name] = true
+			}
+			for _, stmt := range blk.Stmts {
+				if name, ok := write(stmt); ok {
+					if _, ok := locals[name]; ok {
+						o[name] = true
+					}
+				}
+			}
+			if !equal(o, out[blk.Index]) {
+				out[blk.Index] = o
+				changed = true
+			}
+		}
+	}
+	return in
+}
+
+// intersect returns a ∩ b. nil a is the universal set.
+func intersect(a, b assigned) assigned {
+	s := assigned{}
+	for name := range b {
+		if a == nil || a[name] {
+			s[name] = true
+		}
+	}
+	return s
+}
+
+func equal(a, b assigned) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if !b[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// write returns the variable assigned by stmt.
+func write(stmt element.Statement) (string, bool) {
+	if ls, ok := stmt.(*element.LetStatement); ok && ls.Lexp == nil {
+		return string(ls.Vn), true
+	}
+	return "", false
+}
+
+// reads returns the variables read by stmt in the order of evaluation.
+// Only the condition of IfStatement and WhileStatement is read in their Block.
+func reads(stmt element.Statement) []ref {
+	var refs []ref
+	switch s := stmt.(type) {
+	case *element.LetStatement:
+		if s.Lexp != nil {
+			refs = readExpression(refs, s.Lexp)
+		}
+		refs = readExpression(refs, &s.Rexp)
+		if s.Lexp != nil {
+			refs = append(refs, ref{string(s.Vn), s.VnPos})
+		}
+	case *element.IfStatement:
+		refs = readExpression(refs, &s.LExp)
+	case *element.WhileStatement:
+		refs = readExpression(refs, &s.Exp)
+	case *element.DoStatement:
+		refs = readSubroutineCall(refs, s.Sub)
+	case *element.ReturnStatement:
+		if s.Exp != nil {
+			refs = readExpression(refs, s.Exp)
+		}
+	}
+	return refs
+}
+
+func readExpression(refs []ref, exp *element.Expression) []ref {
+	refs = readTerm(refs, exp.Term)
+	for _, v := range exp.Next {
+		refs = readTerm(refs, v.Term)
+	}
+	return refs
+}
+
+func readTerm(refs []ref, term element.Term) []ref {
+	switch t := term.(type) {
+	case *element.VarName:
+		refs = append(refs, ref{string(t.V), t.Pos})
+	case *element.CallIndex:
+		refs = readExpression(refs, &t.Exp)
+		refs = append(refs, ref{string(t.Vn), t.VnPos})
+	case *element.SubroutineCall:
+		refs = readSubroutineCall(refs, t)
+	case *element.Args:
+		refs = readExpression(refs, &t.Exp)
+	case *element.UopTerm:
+		refs = readTerm(refs, t.Term)
+	}
+	return refs
+}
+
+// readSubroutineCall reads the object of varName.subroutineName(...) and the arguments.
+// className is not a local, so it is skipped by the caller.
+func readSubroutineCall(refs []ref, sbc *element.SubroutineCall) []ref {
+	if sbc.Dot != "" {
+		refs = append(refs, ref{string(sbc.Name), sbc.Pos})
+	}
+	for i := range sbc.ExpL {
+		refs = readExpression(refs, &sbc.ExpL[i])
+	}
+	return refs
+}
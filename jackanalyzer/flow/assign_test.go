@@ -0,0 +1,83 @@
+package flow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAssignments(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			"assigned",
+			`class Main {
+  function int f(int n) {
+    var int x, y;
+    var Array a;
+    let a = Array.new(n);
+    let y = 0;
+    if (n) { let x = 1; } else { let x = 2; }
+    while (n > 0) { let y = x; let n = n - 1; }
+    let a[x] = y;
+    return a[0];
+  }
+}`,
+			nil,
+		},
+		{
+			"uninitialized",
+			`class Main {
+  field int k;
+  function void f(int n) {
+    var Point p;
+    var int x, y, z;
+    var Array a;
+    do p.draw();
+    if (n) { let x = 1; }
+    let y = x + x;
+    let a[0] = 1;
+    while (n) { let z = 1; let n = z; }
+    do Output.printInt(z + y);
+    let k = 0;
+    return;
+  }
+}`,
+			[]string{
+				"7:8: warning: local variable 'p' may be used before assignment",
+				"9:13: warning: local variable 'x' may be used before assignment",
+				"10:9: warning: local variable 'a' may be used before assignment",
+				"12:24: warning: local variable 'z' may be used before assignment",
+			},
+		},
+		{
+			"never read",
+			`class Main {
+  function void f() {
+    var int x, y;
+    var String s;
+    let x = 1;
+    let s = "s";
+    let x = 2;
+    return;
+    do Output.printString(s);
+  }
+}`,
+			[]string{"3:13: warning: local variable 'x' is assigned but never used"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckAssignments(parse(t, tt.s))
+			var gots []string
+			for _, d := range got {
+				gots = append(gots, d.String())
+			}
+			if strings.Join(gots, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("CheckAssignments() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
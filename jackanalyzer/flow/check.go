@@ -0,0 +1,47 @@
+package flow
+
+import (
+	"fmt"
+	"jackanalyzer/check"
+	"jackanalyzer/element"
+	"sort"
+)
+
+// Check builds Graph of every subroutine of cl and reports
+// the subroutine which may fall off the end without return as ERROR,
+// the unreachable statements and the while(true) loops without exit as WARNING.
+func Check(cl *element.Class) check.Diagnostics {
+	var diags check.Diagnostics
+	for _, sd := range cl.Sds {
+		g := Build(sd)
+		if g.FallsOff {
+			diags = append(diags, check.Diagnostic{
+				Pos:      sd.Sb.RBPos,
+				Severity: check.ERROR,
+				Msg:      fmt.Sprintf("subroutine '%s' may fall off the end without return", string(sd.Sn)),
+			})
+		}
+		for _, d := range g.Dead {
+			after := "return"
+			if d.Cause == "loop" {
+				after = "infinite loop"
+			}
+			diags = append(diags, check.Diagnostic{
+				Pos:      StatementPos(d.Stmt),
+				Severity: check.WARNING,
+				Msg:      "statement unreachable after " + after,
+			})
+		}
+		for _, ws := range g.InfiniteLoops {
+			diags = append(diags, check.Diagnostic{
+				Pos:      ws.Pos,
+				Severity: check.WARNING,
+				Msg:      "while(true) loop without exit",
+			})
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	})
+	return diags
+}
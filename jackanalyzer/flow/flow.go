@@ -0,0 +1,208 @@
+package flow
+
+import (
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+)
+
+// Block is a basic block of Graph.
+// IfStatement and WhileStatement end their Block, whose successors are the branches.
+type Block struct {
+	Index int
+	Stmts []element.Statement
+	Succs []*Block
+	Preds []*Block
+	Live  bool // reachable from Graph.Entry
+}
+
+// Dead is the first statement of unreachable code.
+type Dead struct {
+	Stmt  element.Statement
+	Cause string // 'return' | 'loop'
+}
+
+// Graph is the control-flow graph of a subroutine.
+type Graph struct {
+	Entry  *Block
+	Exit   *Block // reached by the return statements and by falling off the end
+	Blocks []*Block
+
+	// FallsOff reports whether the end of the subroutine body is reachable without return.
+	FallsOff bool
+	// Dead is the unreachable statements following a live return or a while(true) loop.
+	Dead []Dead
+	// InfiniteLoops is the live while(true) loops without a reachable return.
+	InfiniteLoops []*element.WhileStatement
+}
+
+// builder builds Graph from the statements.
+type builder struct {
+	g       *Graph
+	cur     *Block // nil after return or while(true)
+	cause   string // why cur is nil, empty if the terminating block was not live
+	returns int    // the number of live return statements
+}
+
+// Build returns the control-flow graph of sd.
+func Build(sd *element.SubroutineDec) *Graph {
+	b := &builder{g: &Graph{}}
+	b.g.Entry = b.newBlock()
+	b.g.Entry.Live = true
+	b.g.Exit = &Block{}
+	b.cur = b.g.Entry
+	b.statements(sd.Sb.Stmts)
+	if b.cur != nil {
+		link(b.cur, b.g.Exit)
+		b.g.FallsOff = b.cur.Live
+	}
+	b.g.Exit.Index = len(b.g.Blocks)
+	b.g.Exit.Live = anyLive(b.g.Exit.Preds)
+	b.g.Blocks = append(b.g.Blocks, b.g.Exit)
+	return b.g
+}
+
+func (b *builder) statements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		if b.cur == nil {
+			if b.cause != "" {
+				b.g.Dead = append(b.g.Dead, Dead{Stmt: stmt, Cause: b.cause})
+				b.cause = ""
+			}
+			b.cur = b.newBlock()
+		}
+		switch s := stmt.(type) {
+		case *element.LetStatement, *element.DoStatement:
+			b.cur.Stmts = append(b.cur.Stmts, s)
+		case *element.ReturnStatement:
+			b.cur.Stmts = append(b.cur.Stmts, s)
+			link(b.cur, b.g.Exit)
+			if b.cur.Live {
+				b.returns++
+			}
+			b.terminate("return")
+		case *element.IfStatement:
+			b.ifStatement(s)
+		case *element.WhileStatement:
+			b.whileStatement(s)
+		}
+	}
+}
+
+// ifStatement joins the ends of the branches.
+func (b *builder) ifStatement(s *element.IfStatement) {
+	cond := b.cur
+	cond.Stmts = append(cond.Stmts, s)
+
+	b.cur = b.newBlock(cond)
+	b.statements(s.Stmts)
+	ends := []*Block{b.cur}
+	if s.Else != "" {
+		b.cur = b.newBlock(cond)
+		b.statements(s.EStmts)
+		ends = append(ends, b.cur)
+	} else {
+		ends = append(ends, cond)
+	}
+
+	var preds []*Block
+	for _, end := range ends {
+		if end != nil {
+			preds = append(preds, end)
+		}
+	}
+	if len(preds) == 0 {
+		b.cur = nil
+		return
+	}
+	b.cur = b.newBlock(preds...)
+}
+
+// whileStatement loops back to the condition.
+// while(true) leaves only by return.
+func (b *builder) whileStatement(s *element.WhileStatement) {
+	cond := b.newBlock(b.cur)
+	cond.Stmts = append(cond.Stmts, s)
+
+	returns := b.returns
+	b.cur = b.newBlock(cond)
+	b.statements(s.Stmts)
+	if b.cur != nil {
+		link(b.cur, cond)
+	}
+
+	if isTrue(&s.Exp) {
+		if cond.Live && b.returns == returns {
+			b.g.InfiniteLoops = append(b.g.InfiniteLoops, s)
+		}
+		b.cur = cond
+		b.terminate("loop")
+		return
+	}
+	b.cur = b.newBlock(cond)
+}
+
+// terminate makes the following statements unreachable.
+func (b *builder) terminate(cause string) {
+	b.cause = ""
+	if b.cur.Live {
+		b.cause = cause
+	}
+	b.cur = nil
+}
+
+// newBlock returns a new Block following preds.
+// The Block is live if any of preds is live.
+func (b *builder) newBlock(preds ...*Block) *Block {
+	blk := &Block{Index: len(b.g.Blocks)}
+	for _, p := range preds {
+		link(p, blk)
+	}
+	blk.Live = anyLive(preds)
+	b.g.Blocks = append(b.g.Blocks, blk)
+	return blk
+}
+
+func link(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func anyLive(blks []*Block) bool {
+	for _, blk := range blks {
+		if blk.Live {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrue reports whether exp is the constant true, possibly in parentheses.
+func isTrue(exp *element.Expression) bool {
+	if len(exp.Next) != 0 {
+		return false
+	}
+	switch t := exp.Term.(type) {
+	case *element.KeywordConstant:
+		return t.V == "true"
+	case *element.Args:
+		return isTrue(&t.Exp)
+	}
+	return false
+}
+
+// StatementPos returns the position of the keyword of stmt.
+func StatementPos(stmt element.Statement) token.Pos {
+	switch s := stmt.(type) {
+	case *element.LetStatement:
+		return s.Pos
+	case *element.IfStatement:
+		return s.Pos
+	case *element.WhileStatement:
+		return s.Pos
+	case *element.DoStatement:
+		return s.Pos
+	case *element.ReturnStatement:
+		return s.Pos
+	}
+	return token.Pos{}
+}
@@ -0,0 +1,137 @@
+package flow
+
+import (
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, s string) *element.Class {
+	t.Helper()
+	cl, err := cmplengn.ParseScanner(tokenizer.NewScanner(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+func TestBuild(t *testing.T) {
+	cl := parse(t, `class Main {
+  function int f(int x) {
+    let x = 1;
+    if (x) { let x = 2; } else { return x; }
+    while (x) { let x = x - 1; }
+    return x;
+  }
+}`)
+	g := Build(cl.Sds[0])
+	// entry(let, if), then, else(return), join(), cond(while), body, after(return), exit
+	if len(g.Blocks) != 8 {
+		t.Fatalf("len(Blocks) = %v, want 8", len(g.Blocks))
+	}
+	if g.FallsOff || len(g.Dead) != 0 || len(g.InfiniteLoops) != 0 {
+		t.Errorf("Build() = %+v, want no findings", g)
+	}
+	if n := len(g.Entry.Stmts); n != 2 {
+		t.Errorf("len(Entry.Stmts) = %v, want 2", n)
+	}
+	if n := len(g.Entry.Succs); n != 2 {
+		t.Errorf("len(Entry.Succs) = %v, want 2", n)
+	}
+	if n := len(g.Exit.Preds); n != 2 {
+		t.Errorf("len(Exit.Preds) = %v, want 2", n)
+	}
+	cond := g.Blocks[4]
+	if _, ok := cond.Stmts[0].(*element.WhileStatement); !ok || len(cond.Preds) != 2 || len(cond.Succs) != 2 {
+		t.Errorf("while block = %+v", cond)
+	}
+	for _, blk := range g.Blocks {
+		if !blk.Live {
+			t.Errorf("block %d is not live", blk.Index)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			"no diagnostics",
+			`class Main {
+  function int f(int x) {
+    if (x) { return 1; } else { return 2; }
+  }
+  function void g() {
+    while (true) {
+      if (Keyboard.keyPressed()) { return; }
+    }
+  }
+  function void h() {
+    while ((true)) { do Sys.wait(1); }
+  }
+}`,
+			[]string{"11:5: warning: while(true) loop without exit"},
+		},
+		{
+			"missing return",
+			`class Main {
+  function int f(int x) {
+    if (x) { return 1; }
+  }
+  function void g() {
+    while (false) { return; }
+  }
+  function void h() {}
+}`,
+			[]string{
+				"4:3: subroutine 'f' may fall off the end without return",
+				"7:3: subroutine 'g' may fall off the end without return",
+				"8:22: subroutine 'h' may fall off the end without return",
+			},
+		},
+		{
+			"unreachable",
+			`class Main {
+  function void f(int x) {
+    return;
+    let x = 1;
+    return;
+    do f(x);
+  }
+  function void g(int x) {
+    if (x) { return; } else { return; }
+    while (x) {}
+  }
+  function void h(int x) {
+    while (x) { return; do h(x); }
+    while (true) {}
+    return;
+  }
+}`,
+			[]string{
+				"4:5: warning: statement unreachable after return",
+				"10:5: warning: statement unreachable after return",
+				"13:25: warning: statement unreachable after return",
+				"14:5: warning: while(true) loop without exit",
+				"15:5: warning: statement unreachable after infinite loop",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(parse(t, tt.s))
+			var gots []string
+			for _, d := range got {
+				gots = append(gots, d.String())
+			}
+			if strings.Join(gots, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Check() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
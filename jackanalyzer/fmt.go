@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"jackanalyzer/diff"
+	"jackanalyzer/format"
+	"os"
+)
+
+// runFmt is the entry point of 'jackanalyzer fmt'. It returns the exit code.
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jackanalyzer fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	var write, showDiff bool
+	var conf format.Config
+	fs.BoolVar(&write, "w", false, "write the result to the source file")
+	fs.BoolVar(&showDiff, "d", false, "print the diff")
+	fs.BoolVar(&conf.SplitDecls, "split-decls", false, "declare a variable per line")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	files, err := jackFiles(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	failed := 0
+	for _, f := range files {
+		if err := formatFile(f, conf, write, showDiff, stdout); err != nil {
+			failed++
+			fmt.Fprintln(stderr, errorMessage(f, err))
+		}
+	}
+	if failed != 0 {
+		fmt.Fprintf(stderr, "jackanalyzer: %d of %d files failed\n", failed, len(files))
+		return 1
+	}
+	return 0
+}
+
+// formatFile formats the file of path.
+// It prints the formatted source unless write or showDiff is true.
+func formatFile(path string, conf format.Config, write, showDiff bool, stdout io.Writer) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	res, err := format.Source(src, conf)
+	if err != nil {
+		return err
+	}
+	if !write && !showDiff {
+		_, err := stdout.Write(res)
+		return err
+	}
+	if showDiff {
+		if _, err := stdout.Write(diff.Unified(path+".orig", path, src, res)); err != nil {
+			return err
+		}
+	}
+	if write && string(res) != string(src) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, res, fi.Mode().Perm())
+	}
+	return nil
+}
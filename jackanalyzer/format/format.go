@@ -0,0 +1,382 @@
+package format
+
+import (
+	"bytes"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// indent is a level of indentation.
+const indent = "    "
+
+// Config configures Source.
+type Config struct {
+	// SplitDecls prints a declaration per variable:
+	// 'var int x, y;' is printed as 'var int x;' and 'var int y;'.
+	SplitDecls bool
+}
+
+// printer prints the canonical source of a class.
+type printer struct {
+	conf     Config
+	toks     []*token.Token // the tokens of the source
+	lines    []string
+	depth    int
+	comments []comment // the comments not printed yet
+	srcLine  int       // the source line of the last printed element
+	open     bool      // the last line opens a block
+}
+
+// comment is a comment of the source.
+type comment struct {
+	token.Pos
+	Text   string // without the carriage returns
+	Inline bool   // a token precedes the comment on the same line
+}
+
+// Source formats the Jack source src:
+// 4 spaces of indentation, '{' at the end of the line, '} else {',
+// a space around the binary operators and after ',',
+// a blank line between the declarations
+// and at most one of the blank lines between the statements.
+// The comments are kept at their own line or at the end of the line,
+// and the comments inside a declaration or a statement at the end of its line.
+//
+// It returns *tokenizer.Error or cmplengn.ErrorList if src is not a valid class.
+func Source(src []byte, conf Config) ([]byte, error) {
+	tz := tokenizer.New(bytes.NewReader(src), tokenizer.WithTrivia())
+	head, err := tz.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	var toks []*token.Token
+	var comments []comment
+	for t := head.Next; t != nil; t = t.Next {
+		toks = append(toks, t)
+		comments = appendComments(comments, t.Leading, false)
+		comments = appendComments(comments, t.Trailing, true)
+	}
+	comments = appendComments(comments, tz.EOFTrivia(), false)
+
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{conf: conf, toks: toks, comments: comments}
+	p.class(cl)
+	p.flush(token.Pos{Offset: len(src) + 1}, true)
+	return []byte(strings.Join(p.lines, "\n") + "\n"), nil
+}
+
+// appendComments appends the comments of trivia to comments.
+func appendComments(comments []comment, trivia []token.Trivia, inline bool) []comment {
+	for _, t := range trivia {
+		if t.Kind == token.WHITESPACE {
+			continue
+		}
+		comments = append(comments, comment{
+			Pos:    t.Pos,
+			Text:   strings.Replace(t.Text, "\r", "", -1),
+			Inline: inline,
+		})
+	}
+	return comments
+}
+
+func (p *printer) class(cl *element.Class) {
+	start := p.token(cl.CnPos, -1)
+	p.flush(start, true)
+	p.println(start, "class "+string(cl.Cn)+" {")
+	p.inside(p.symbol(cl.CnPos, "{"))
+	p.depth++
+	for _, cvd := range cl.Cvds {
+		start := p.token(cvd.VnPos, -2)
+		p.flush(start, true)
+		names := []string{string(cvd.Vn)}
+		for _, v := range cvd.Vns {
+			names = append(names, string(v.Vn))
+		}
+		p.decl(start, string(cvd.Modi)+" "+element.TypeName(cvd.Vt), names)
+		p.inside(p.symbol(cvd.VnPos, ";"))
+	}
+	for i, sd := range cl.Sds {
+		if i > 0 || len(cl.Cvds) > 0 {
+			p.trailing(p.token(sd.SnPos, -2))
+			p.blank()
+		}
+		p.subroutineDec(sd)
+	}
+	p.flush(cl.RBracePos, false)
+	p.depth--
+	p.println(cl.RBracePos, "}")
+}
+
+// decl prints 'head name, name;' or a line per name if SplitDecls.
+func (p *printer) decl(pos token.Pos, head string, names []string) {
+	if p.conf.SplitDecls {
+		for _, name := range names {
+			p.println(pos, head+" "+name+";")
+		}
+		return
+	}
+	p.println(pos, head+" "+strings.Join(names, ", ")+";")
+}
+
+func (p *printer) subroutineDec(sd *element.SubroutineDec) {
+	start := p.token(sd.SnPos, -2)
+	p.flush(start, false)
+	var params []string
+	if sd.Pl != nil {
+		params = append(params, element.TypeName(sd.Pl.Type)+" "+string(sd.Pl.Vn))
+		for _, v := range sd.Pl.Next {
+			params = append(params, element.TypeName(v.Type)+" "+string(v.Vn))
+		}
+	}
+	p.println(start, string(sd.Modi)+" "+element.TypeName(sd.St)+" "+string(sd.Sn)+"("+strings.Join(params, ", ")+") {")
+	p.inside(p.symbol(sd.SnPos, "{"))
+	p.depth++
+	for _, vd := range sd.Sb.Vd {
+		start := p.token(vd.VnPos, -2)
+		p.flush(start, true)
+		names := []string{string(vd.Vn)}
+		for _, v := range vd.Vns {
+			names = append(names, string(v.Vn))
+		}
+		p.decl(start, "var "+element.TypeName(vd.Vt), names)
+		p.inside(p.symbol(vd.VnPos, ";"))
+	}
+	p.statements(sd.Sb.Stmts)
+	p.flush(sd.Sb.RBPos, false)
+	p.depth--
+	p.println(sd.Sb.RBPos, "}")
+}
+
+func (p *printer) statements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			p.flush(s.Pos, true)
+			lhs := string(s.Vn)
+			if s.Lexp != nil {
+				lhs += "[" + expression(s.Lexp) + "]"
+			}
+			p.println(s.Pos, "let "+lhs+" = "+expression(&s.Rexp)+";")
+			p.inside(p.symbol(s.Pos, ";"))
+		case *element.IfStatement:
+			p.flush(s.Pos, true)
+			p.println(s.Pos, "if ("+expression(&s.LExp)+") {")
+			p.inside(p.symbol(s.Pos, "{"))
+			p.block(s.Stmts, s.RBPos)
+			if s.Else != "" {
+				p.lines[len(p.lines)-1] += " else {"
+				p.open = true
+				p.inside(p.symbol(s.RBPos, "{"))
+				p.block(s.EStmts, s.ERBPos)
+			}
+		case *element.WhileStatement:
+			p.flush(s.Pos, true)
+			p.println(s.Pos, "while ("+expression(&s.Exp)+") {")
+			p.inside(p.symbol(s.Pos, "{"))
+			p.block(s.Stmts, s.RBPos)
+		case *element.DoStatement:
+			p.flush(s.Pos, true)
+			p.println(s.Pos, "do "+subroutineCall(s.Sub)+";")
+			p.inside(p.symbol(s.Pos, ";"))
+		case *element.ReturnStatement:
+			p.flush(s.Pos, true)
+			if s.Exp == nil {
+				p.println(s.Pos, "return;")
+			} else {
+				p.println(s.Pos, "return "+expression(s.Exp)+";")
+			}
+			p.inside(p.symbol(s.Pos, ";"))
+		}
+	}
+}
+
+// block prints the statements of if, else or while and '}' at rbPos.
+func (p *printer) block(stmts []element.Statement, rbPos token.Pos) {
+	p.depth++
+	p.statements(stmts)
+	p.flush(rbPos, false)
+	p.depth--
+	p.println(rbPos, "}")
+}
+
+// trailing appends the comments before pos following a token on the same line
+// to the last line.
+func (p *printer) trailing(pos token.Pos) {
+	for len(p.comments) > 0 && p.comments[0].Offset < pos.Offset {
+		c := p.comments[0]
+		if !c.Inline || strings.Contains(c.Text, "\n") || len(p.lines) == 0 || p.lines[len(p.lines)-1] == "" {
+			return
+		}
+		p.comments = p.comments[1:]
+		p.lines[len(p.lines)-1] += " " + c.Text
+		p.advance(c.Line)
+	}
+}
+
+// inside appends the comments before end, the last token of the element
+// printed at the last line, to the last line.
+// The comments inside the element are kept at its line even if they are at their own lines in the source.
+func (p *printer) inside(end token.Pos) {
+	for len(p.comments) > 0 && p.comments[0].Offset < end.Offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.lines[len(p.lines)-1] += " " + c.Text
+		p.advance(c.Line + strings.Count(c.Text, "\n"))
+	}
+	p.advance(end.Line)
+}
+
+// advance makes line the source line of the last printed element
+// unless the element already reaches a later line.
+func (p *printer) advance(line int) {
+	if line > p.srcLine {
+		p.srcLine = line
+	}
+}
+
+// token returns the position of the token n tokens after the token at pos.
+func (p *printer) token(pos token.Pos, n int) token.Pos {
+	i := sort.Search(len(p.toks), func(i int) bool { return p.toks[i].Offset >= pos.Offset }) + n
+	if i < 0 || i >= len(p.toks) {
+		return pos
+	}
+	return p.toks[i].Pos
+}
+
+// symbol returns the position of the first symbol s after pos.
+func (p *printer) symbol(pos token.Pos, s string) token.Pos {
+	i := sort.Search(len(p.toks), func(i int) bool { return p.toks[i].Offset > pos.Offset })
+	for ; i < len(p.toks); i++ {
+		if p.toks[i].TokenType == token.SYMBOL && p.toks[i].Symbol == s {
+			return p.toks[i].Pos
+		}
+	}
+	return pos
+}
+
+// flush prints the comments before pos.
+// The comment following a token on the same line is appended to the last line,
+// the others are printed at their own lines.
+// If gaps is true, a blank line in the source between the elements is kept.
+func (p *printer) flush(pos token.Pos, gaps bool) {
+	for len(p.comments) > 0 && p.comments[0].Offset < pos.Offset {
+		p.trailing(pos)
+		if len(p.comments) == 0 || p.comments[0].Offset >= pos.Offset {
+			break
+		}
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if gaps {
+			p.gap(c.Pos)
+		}
+		for i, l := range strings.Split(c.Text, "\n") {
+			if i > 0 {
+				l = continuation(l, c.Column-1)
+			}
+			p.add(l)
+		}
+		p.open = false
+		p.srcLine = c.Line + strings.Count(c.Text, "\n")
+	}
+	if gaps {
+		p.gap(pos)
+	}
+}
+
+// continuation returns the continuation line l of /* */ starting at column col+1
+// without the indentation of the comment.
+// The leading '*' is aligned under the '*' of "/*" like gofmt,
+// the other lines keep their indentation relative to the comment.
+func continuation(l string, col int) string {
+	l = strings.TrimRight(l, " \t")
+	if t := strings.TrimLeft(l, " \t"); strings.HasPrefix(t, "*") {
+		return " " + t
+	}
+	i := 0
+	for i < len(l) && i < col && (l[i] == ' ' || l[i] == '\t') {
+		i++
+	}
+	return l[i:]
+}
+
+// gap prints a blank line if the source has a blank line before pos.
+func (p *printer) gap(pos token.Pos) {
+	if p.open || p.srcLine == 0 || pos.Line <= p.srcLine+1 {
+		return
+	}
+	p.blank()
+}
+
+// blank prints a blank line unless the last line is blank.
+func (p *printer) blank() {
+	if len(p.lines) > 0 && p.lines[len(p.lines)-1] != "" {
+		p.lines = append(p.lines, "")
+	}
+}
+
+// println prints s at the current indentation for the element at pos.
+func (p *printer) println(pos token.Pos, s string) {
+	p.add(s)
+	p.open = strings.HasSuffix(s, "{")
+	p.srcLine = pos.Line
+}
+
+func (p *printer) add(s string) {
+	if s == "" {
+		p.lines = append(p.lines, "")
+		return
+	}
+	p.lines = append(p.lines, strings.Repeat(indent, p.depth)+s)
+}
+
+func expression(exp *element.Expression) string {
+	var b strings.Builder
+	b.WriteString(term(exp.Term))
+	for _, v := range exp.Next {
+		b.WriteString(" " + string(v.Bop) + " " + term(v.Term))
+	}
+	return b.String()
+}
+
+func term(t element.Term) string {
+	switch t := t.(type) {
+	case *element.IntegerConstant:
+		return strconv.Itoa(int(t.V))
+	case *element.StringConstant:
+		return `"` + string(t.V) + `"`
+	case *element.KeywordConstant:
+		return string(t.V)
+	case *element.VarName:
+		return string(t.V)
+	case *element.CallIndex:
+		return string(t.Vn) + "[" + expression(&t.Exp) + "]"
+	case *element.SubroutineCall:
+		return subroutineCall(t)
+	case *element.Args:
+		return "(" + expression(&t.Exp) + ")"
+	case *element.UopTerm:
+		return string(t.Uop) + term(t.Term)
+	}
+	return ""
+}
+
+func subroutineCall(sbc *element.SubroutineCall) string {
+	name := string(sbc.Sn)
+	if sbc.Dot != "" {
+		name = string(sbc.Name) + "." + name
+	}
+	args := make([]string, len(sbc.ExpL))
+	for i := range sbc.ExpL {
+		args[i] = expression(&sbc.ExpL[i])
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
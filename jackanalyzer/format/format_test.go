@@ -0,0 +1,267 @@
+package format
+
+import (
+	"bytes"
+	"io/ioutil"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		src  string
+		want string
+	}{
+		{
+			"indentation and spacing",
+			Config{},
+			`class Main{field int x,y;
+function void main(){var int i;let i=-1+2*(3-x);
+if(i<0){do Output.printInt(i,"s");}else{while(~(i=0)){let i=i-1;}}
+return;}}`,
+			`class Main {
+    field int x, y;
+
+    function void main() {
+        var int i;
+        let i = -1 + 2 * (3 - x);
+        if (i < 0) {
+            do Output.printInt(i, "s");
+        } else {
+            while (~(i = 0)) {
+                let i = i - 1;
+            }
+        }
+        return;
+    }
+}
+`,
+		},
+		{
+			"split declarations",
+			Config{SplitDecls: true},
+			`class Main {
+  static int a, b;
+  method void f() { var Array x, y; return; }
+}`,
+			`class Main {
+    static int a;
+    static int b;
+
+    method void f() {
+        var Array x;
+        var Array y;
+        return;
+    }
+}
+`,
+		},
+		{
+			"comments and blank lines",
+			Config{},
+			`// header
+
+/** Main. */
+class Main {
+  field int x; // x
+  function void main() { // body
+
+
+    let x = 1;   /* one */
+    let x = 2;
+    if (x) {
+      // empty
+    }
+    return;
+    // end
+  }
+  /**
+   * doc
+   */
+  method void f() {
+    return;
+  }
+}
+// tail
+`,
+			`// header
+
+/** Main. */
+class Main {
+    field int x; // x
+
+    function void main() { // body
+        let x = 1; /* one */
+        let x = 2;
+        if (x) {
+            // empty
+        }
+        return;
+        // end
+    }
+
+    /**
+     * doc
+     */
+    method void f() {
+        return;
+    }
+}
+// tail
+`,
+		},
+		{
+			"indented block comment",
+			Config{},
+			`class Main {
+  /* the loop:
+       while (x) {
+           let x = x - 1;
+       }
+     ends */
+  function void f() {
+    return;
+  }
+}
+`,
+			`class Main {
+    /* the loop:
+         while (x) {
+             let x = x - 1;
+         }
+       ends */
+    function void f() {
+        return;
+    }
+}
+`,
+		},
+		{
+			"comments inside elements",
+			Config{},
+			`class Main {
+  static /* s */ int a;
+  function /* f */ void f(int a /* p */,
+      // b
+      int b) {
+    let a = a +
+      // own
+      b;
+    do Output.printInt(a, // first
+      b);
+    return;
+  }
+}
+`,
+			`class Main {
+    static int a; /* s */
+
+    function void f(int a, int b) { /* f */ /* p */ // b
+        let a = a + b; // own
+        do Output.printInt(a, b); // first
+        return;
+    }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source([]byte(tt.src), tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Source() = \n%s\nwant \n%s", got, tt.want)
+			}
+			again, err := Source(got, tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("Source() is not idempotent: \n%s", again)
+			}
+		})
+	}
+}
+
+func TestSource_error(t *testing.T) {
+	if _, err := Source([]byte("class Main { function void f( }"), Config{}); err == nil {
+		t.Error("Source() error = nil, want syntax error")
+	}
+	if _, err := Source([]byte(`class Main { "a`), Config{}); err == nil {
+		t.Error("Source() error = nil, want tokenize error")
+	}
+}
+
+// TestSource_testdata checks that formatting keeps the class of every test program.
+func TestSource_testdata(t *testing.T) {
+	files, err := filepath.Glob("../cmplengn/testdata/*/*.jack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test programs")
+	}
+	for _, f := range files {
+		t.Run(f, func(t *testing.T) {
+			src, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Source(src, Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(parse(t, got), parse(t, src)) {
+				t.Errorf("Source() changed the class:\n%s", got)
+			}
+			again, err := Source(got, Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("Source() is not idempotent")
+			}
+		})
+	}
+}
+
+// parse returns the class of src without the positions.
+func parse(t *testing.T, src []byte) *element.Class {
+	t.Helper()
+	cl, err := cmplengn.ParseScanner(tokenizer.NewScanner(bytes.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearPos(reflect.ValueOf(cl))
+	return cl
+}
+
+func clearPos(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPos(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPos(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(token.Pos{}) {
+			v.Set(reflect.ValueOf(token.Pos{}))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			clearPos(v.Field(i))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"jackanalyzer/lsp"
+)
+
+// runLSP is the entry point of 'jackanalyzer lsp'. It returns the exit code.
+func runLSP(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jackanalyzer lsp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if err := lsp.Serve(stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,402 @@
+package lsp
+
+import (
+	"jackanalyzer/doc"
+	"jackanalyzer/element"
+	"jackanalyzer/program"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// target is the declaration an identifier refers to.
+type target struct {
+	class *element.Class         // the class, or the class of sub
+	sub   *element.SubroutineDec // the subroutine
+	sym   *symboltable.Symbol    // the variable declared in the document
+	pos   token.Pos              // the position of the variable name
+}
+
+// resolve returns the identifier at offset and its declaration:
+//  varName.subroutineName, className.subroutineName  the subroutine of the class
+//  subroutineName(                                   the subroutine of the class of d
+//  varName                                           the variable in the scope
+//  className                                         the class
+func (s *Server) resolve(d *document, p *program.Program, offset int) (*token.Token, *target) {
+	t := d.tokenAt(offset)
+	if t == nil || t.TokenType != token.IDENTIFIER || d.file.Class == nil {
+		return nil, nil
+	}
+	i := sort.Search(len(d.file.Tokens), func(i int) bool { return d.file.Tokens[i].Offset >= t.Offset })
+	at := func(i int) *token.Token {
+		if i < 0 || i >= len(d.file.Tokens) {
+			return nil
+		}
+		return d.file.Tokens[i]
+	}
+	name := t.Identifier
+	sd := enclosing(d.file.Class, t.Offset)
+	st := d.file.Symbols
+	if sd != nil {
+		st = d.file.Scope(sd)
+	}
+
+	if isSymbol(at(i-1), ".") && at(i-2) != nil && at(i-2).TokenType == token.IDENTIFIER {
+		cn := at(i - 2).Identifier
+		if sym, ok := st.Lookup(cn); ok {
+			cn = sym.Type
+		}
+		return t, subroutineTarget(p, cn, name)
+	}
+	if isSymbol(at(i+1), "(") {
+		return t, subroutineTarget(p, string(d.file.Class.Cn), name)
+	}
+	if sym, ok := st.Lookup(name); ok {
+		if pos, ok := declPos(d.file.Class, sd, name); ok {
+			return t, &target{sym: sym, pos: pos}
+		}
+	}
+	if cl, ok := p.Class(name); ok {
+		return t, &target{class: cl}
+	}
+	return t, nil
+}
+
+func subroutineTarget(p *program.Program, cn, sn string) *target {
+	cl, ok := p.Class(cn)
+	if !ok {
+		return nil
+	}
+	for _, sd := range cl.Sds {
+		if string(sd.Sn) == sn {
+			return &target{class: cl, sub: sd}
+		}
+	}
+	return nil
+}
+
+// enclosing returns the subroutine of cl containing offset.
+func enclosing(cl *element.Class, offset int) *element.SubroutineDec {
+	for _, sd := range cl.Sds {
+		if sd.SnPos.Offset <= offset && offset <= sd.Sb.RBPos.Offset {
+			return sd
+		}
+	}
+	return nil
+}
+
+// declPos returns the position of the variable name declared in sd or cl.
+func declPos(cl *element.Class, sd *element.SubroutineDec, name string) (token.Pos, bool) {
+	if sd != nil {
+		if sd.Pl != nil {
+			if string(sd.Pl.Vn) == name {
+				return sd.Pl.VnPos, true
+			}
+			for _, v := range sd.Pl.Next {
+				if string(v.Vn) == name {
+					return v.VnPos, true
+				}
+			}
+		}
+		for _, vd := range sd.Sb.Vd {
+			if pos, ok := varNamePos(append([]*element.NextVns{{Vn: vd.Vn, VnPos: vd.VnPos}}, vd.Vns...), name); ok {
+				return pos, true
+			}
+		}
+	}
+	for _, cvd := range cl.Cvds {
+		if pos, ok := varNamePos(append([]*element.NextVns{{Vn: cvd.Vn, VnPos: cvd.VnPos}}, cvd.Vns...), name); ok {
+			return pos, true
+		}
+	}
+	return token.Pos{}, false
+}
+
+func varNamePos(vars []*element.NextVns, name string) (token.Pos, bool) {
+	for _, v := range vars {
+		if string(v.Vn) == name {
+			return v.VnPos, true
+		}
+	}
+	return token.Pos{}, false
+}
+
+func isSymbol(t *token.Token, sym string) bool {
+	return t != nil && t.TokenType == token.SYMBOL && t.Symbol == sym
+}
+
+// definition returns the location of the declaration at the position,
+// or nil for the Jack OS stubs.
+func (s *Server) definition(p TextDocumentPositionParams) *Location {
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	prog := s.program(d)
+	_, tg := s.resolve(d, prog, d.offset(p.Position))
+	if tg == nil {
+		return nil
+	}
+	if tg.sym != nil {
+		return &Location{URI: d.uri, Range: rangeOf(d.lines, tg.pos, len(tg.sym.Name))}
+	}
+	path := filePath(prog, tg.class)
+	if path == "" {
+		return nil
+	}
+	pos, name := tg.class.CnPos, string(tg.class.Cn)
+	if tg.sub != nil {
+		pos, name = tg.sub.SnPos, string(tg.sub.Sn)
+	}
+	return &Location{URI: pathToURI(path), Range: rangeOf(s.lines(path), pos, len(name))}
+}
+
+// filePath returns the path of the file declaring cl, or "" for the Jack OS stubs.
+func filePath(p *program.Program, cl *element.Class) string {
+	for _, f := range p.Files {
+		if f.Class == cl {
+			return f.Path
+		}
+	}
+	return ""
+}
+
+// hover returns the declaration of the identifier at the position as Jack code
+// and its doc comment.
+func (s *Server) hover(p TextDocumentPositionParams) *Hover {
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	t, tg := s.resolve(d, s.program(d), d.offset(p.Position))
+	if tg == nil {
+		return nil
+	}
+	var decl, comment string
+	switch {
+	case tg.sym != nil:
+		decl = tg.sym.Kind.String() + " " + tg.sym.Type + " " + tg.sym.Name
+	case tg.sub != nil:
+		decl = signature(string(tg.class.Cn), tg.sub)
+		comment = doc.Text(tg.sub.Doc)
+	default:
+		decl = "class " + string(tg.class.Cn)
+		comment = doc.Text(tg.class.Doc)
+	}
+	value := "```jack\n" + decl + "\n```"
+	if comment != "" {
+		value += "\n\n" + comment
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value},
+		Range:    rangeOf(d.lines, t.Pos, utf8.RuneCountInString(t.Lit)),
+	}
+}
+
+// signature returns 'kind type className.subroutineName(type name, ...)'.
+func signature(cn string, sd *element.SubroutineDec) string {
+	return string(sd.Modi) + " " + element.TypeName(sd.St) + " " + cn + "." + string(sd.Sn) + "(" + params(sd) + ")"
+}
+
+func params(sd *element.SubroutineDec) string {
+	if sd.Pl == nil {
+		return ""
+	}
+	ps := []string{element.TypeName(sd.Pl.Type) + " " + string(sd.Pl.Vn)}
+	for _, v := range sd.Pl.Next {
+		ps = append(ps, element.TypeName(v.Type)+" "+string(v.Vn))
+	}
+	return strings.Join(ps, ", ")
+}
+
+// documentSymbol returns the class of the document with its variables and subroutines.
+func (s *Server) documentSymbol(p DocumentSymbolParams) []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok || d.file.Class == nil {
+		return syms
+	}
+	cl := d.file.Class
+	name := rangeOf(d.lines, cl.CnPos, len(cl.Cn))
+	cs := DocumentSymbol{
+		Name:           string(cl.Cn),
+		Kind:           SymbolClass,
+		Range:          Range{Start: name.Start, End: d.end(cl.RBracePos)},
+		SelectionRange: name,
+	}
+	for _, cvd := range cl.Cvds {
+		kind := SymbolField
+		if cvd.Modi == "static" {
+			kind = SymbolVariable
+		}
+		vars := []*element.NextVns{{Vn: cvd.Vn, VnPos: cvd.VnPos}}
+		vars = append(vars, cvd.Vns...)
+		for _, v := range vars {
+			r := rangeOf(d.lines, v.VnPos, len(v.Vn))
+			cs.Children = append(cs.Children, DocumentSymbol{
+				Name:           string(v.Vn),
+				Detail:         string(cvd.Modi) + " " + element.TypeName(cvd.Vt),
+				Kind:           kind,
+				Range:          r,
+				SelectionRange: r,
+			})
+		}
+	}
+	kinds := map[string]SymbolKind{
+		"constructor": SymbolConstructor,
+		"function":    SymbolFunction,
+		"method":      SymbolMethod,
+	}
+	for _, sd := range cl.Sds {
+		name := rangeOf(d.lines, sd.SnPos, len(sd.Sn))
+		cs.Children = append(cs.Children, DocumentSymbol{
+			Name:           string(sd.Sn),
+			Detail:         string(sd.Modi) + " " + element.TypeName(sd.St) + "(" + params(sd) + ")",
+			Kind:           kinds[string(sd.Modi)],
+			Range:          Range{Start: name.Start, End: d.end(sd.Sb.RBPos)},
+			SelectionRange: name,
+		})
+	}
+	return append(syms, cs)
+}
+
+// end returns the position after the '}' at rb, or the end of d if rb is missing.
+func (d *document) end(rb token.Pos) Position {
+	if rb.Line == 0 {
+		return position(d.lines, token.Pos{Line: len(d.lines), Column: len(d.lines[len(d.lines)-1]) + 1})
+	}
+	return rangeOf(d.lines, rb, 1).End
+}
+
+// completion returns the subroutines of 'className.' or 'varName.' before the position:
+// the functions and constructors of the class, or the methods of the type of the variable.
+func (s *Server) completion(p TextDocumentPositionParams) CompletionList {
+	list := CompletionList{Items: []CompletionItem{}}
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return list
+	}
+	offset := d.offset(p.Position)
+	start := offset
+	for start > 0 && isIdent(d.text[start-1]) {
+		start--
+	}
+	if start == 0 || d.text[start-1] != '.' {
+		return list
+	}
+	dot := start - 1
+	recv := dot
+	for recv > 0 && isIdent(d.text[recv-1]) {
+		recv--
+	}
+	cn := d.text[recv:dot]
+	if cn == "" {
+		return list
+	}
+	methods := false
+	if typ, ok := d.varType(recv, cn); ok {
+		cn, methods = typ, true
+	}
+
+	prog := s.program(d)
+	cl, ok := prog.Class(cn)
+	if !ok {
+		return list
+	}
+	kinds := map[string]CompletionItemKind{
+		"constructor": CompletionConstructor,
+		"function":    CompletionFunction,
+		"method":      CompletionMethod,
+	}
+	seen := map[string]bool{}
+	for _, sd := range cl.Sds {
+		if (sd.Modi == "method") != methods || seen[string(sd.Sn)] {
+			continue
+		}
+		seen[string(sd.Sn)] = true
+		item := CompletionItem{
+			Label:  string(sd.Sn),
+			Kind:   kinds[string(sd.Modi)],
+			Detail: signature(cn, sd),
+		}
+		if text := doc.Text(sd.Doc); text != "" {
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: text}
+		}
+		list.Items = append(list.Items, item)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Label < list.Items[j].Label
+	})
+	return list
+}
+
+// varType returns the type of the variable name in the scope at offset.
+// The source being edited often has syntax errors which drop the subroutine from d.file.Class,
+// so the parameters and the locals are read from the tokens.
+func (d *document) varType(offset int, name string) (string, bool) {
+	if typ, ok := localType(d.file.Tokens, offset, name); ok {
+		return typ, true
+	}
+	if d.file.Symbols == nil {
+		return "", false
+	}
+	if s, ok := d.file.Symbols.Lookup(name); ok {
+		return s.Type, true
+	}
+	return "", false
+}
+
+// localType returns the type of the parameter or the local name
+// of the subroutine declared before offset.
+//
+//  ( 'constructor' | 'function' | 'method' ) ( 'void' | type ) subroutineName
+//  '(' ( type varName ( ',' type varName )* )? ')' '{' ( 'var' type varName ( ',' varName )* ';' )*
+func localType(toks []*token.Token, offset int, name string) (string, bool) {
+	start := -1
+	for i, t := range toks {
+		if t.Offset >= offset {
+			break
+		}
+		if t.TokenType == token.KEYWORD && (t.Keyword == token.CONSTRUCTOR || t.Keyword == token.FUNCTION || t.Keyword == token.METHOD) {
+			start = i
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	at := func(i int) *token.Token {
+		if i >= len(toks) || toks[i].Offset >= offset {
+			return nil
+		}
+		return toks[i]
+	}
+	i := start + 3
+	if !isSymbol(at(i), "(") {
+		return "", false
+	}
+	for i++; at(i) != nil && at(i+1) != nil && !isSymbol(at(i), ")"); {
+		if at(i+1).Identifier == name {
+			return at(i).Lit, true
+		}
+		i += 2
+		if isSymbol(at(i), ",") {
+			i++
+		}
+	}
+	// skip ')' '{'
+	for i += 2; at(i) != nil && at(i).Keyword == token.VAR && at(i+1) != nil; i++ {
+		typ := at(i + 1)
+		for i += 2; at(i) != nil && !isSymbol(at(i), ";"); i++ {
+			if at(i).Identifier == name {
+				return typ.Lit, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
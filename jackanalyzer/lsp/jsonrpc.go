@@ -0,0 +1,144 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The error codes of JSON-RPC and LSP.
+const (
+	CodeParseError           = -32700
+	CodeInvalidRequest       = -32600
+	CodeMethodNotFound       = -32601
+	CodeInvalidParams        = -32602
+	CodeInternalError        = -32603
+	CodeServerNotInitialized = -32002
+)
+
+// Message is a JSON-RPC 2.0 request, response or notification.
+// A notification has no ID, a response has no Method.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// ResponseError is the error of a response.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message + " (" + strconv.Itoa(e.Code) + ")"
+}
+
+// Conn reads and writes the messages framed by the Content-Length header.
+// Both the server and an in-process client use it.
+type Conn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// NewConn returns Conn reading from r and writing to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read reads the next message.
+// It returns io.EOF if the input ends between the messages.
+func (c *Conn) Read() (*Message, error) {
+	length := -1
+	for first := true; ; first = false {
+		line, err := c.r.ReadString('\n')
+		if err == io.EOF && first && line == "" {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(line[:i]), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", line[i+1:])
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, &ResponseError{Code: CodeParseError, Message: err.Error()}
+	}
+	return &m, nil
+}
+
+// Write writes m with the Content-Length header.
+func (c *Conn) Write(m *Message) error {
+	m.JSONRPC = "2.0"
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// Call writes the request of method with id and params.
+func (c *Conn) Call(id int, method string, params interface{}) error {
+	return c.write(json.RawMessage(strconv.Itoa(id)), method, params)
+}
+
+// Notify writes the notification of method with params.
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.write(nil, method, params)
+}
+
+func (c *Conn) write(id json.RawMessage, method string, params interface{}) error {
+	m := &Message{ID: id, Method: method}
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		m.Params = p
+	}
+	return c.Write(m)
+}
+
+// Reply writes the response to the request of id.
+// The result is null if both result and rerr are nil.
+func (c *Conn) Reply(id json.RawMessage, result interface{}, rerr *ResponseError) error {
+	m := &Message{ID: id, Error: rerr}
+	if rerr == nil {
+		r, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		m.Result = r
+	}
+	return c.Write(m)
+}
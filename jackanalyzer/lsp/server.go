@@ -0,0 +1,414 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"jackanalyzer/check"
+	"jackanalyzer/program"
+	"jackanalyzer/token"
+	"jackanalyzer/workspace"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrExitWithoutShutdown is returned by Serve if exit is not preceded by shutdown.
+var ErrExitWithoutShutdown = errors.New("lsp: exit without shutdown")
+
+// Server is a Jack language server.
+// It handles the messages one by one, so the handlers need no locking.
+type Server struct {
+	conn        *Conn
+	docs        map[string]*document            // the open documents by URI
+	workspaces  map[string]*workspace.Workspace // the analysis of the directories
+	initialized bool
+	shutdown    bool
+}
+
+// document is an open .jack file.
+type document struct {
+	uri   string
+	path  string
+	text  string
+	lines []string
+	file  *workspace.File // the analysis of text
+}
+
+// Serve runs the language server reading the messages from r and writing to w
+// until the exit notification.
+// It returns nil if exit follows shutdown, ErrExitWithoutShutdown otherwise,
+// or the error of reading the messages.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{
+		conn:       NewConn(r, w),
+		docs:       map[string]*document{},
+		workspaces: map[string]*workspace.Workspace{},
+	}
+	for {
+		m, err := s.conn.Read()
+		var rerr *ResponseError
+		if errors.As(err, &rerr) {
+			if err := s.conn.Reply(json.RawMessage("null"), nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(m); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches m and replies if m is a request.
+func (s *Server) handle(m *Message) error {
+	result, rerr := s.dispatch(m)
+	if m.ID == nil {
+		return nil
+	}
+	return s.conn.Reply(m.ID, result, rerr)
+}
+
+func (s *Server) dispatch(m *Message) (interface{}, *ResponseError) {
+	if !s.initialized && m.Method != "initialize" {
+		return nil, &ResponseError{Code: CodeServerNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &ResponseError{Code: CodeInvalidRequest, Message: "server is shut down"}
+	}
+	switch m.Method {
+	case "initialize":
+		s.initialized = true
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    TextDocumentSyncFull,
+					Save:      SaveOptions{IncludeText: true},
+				},
+				DefinitionProvider:     true,
+				HoverProvider:          true,
+				DocumentSymbolProvider: true,
+				CompletionProvider:     CompletionOptions{TriggerCharacters: []string{"."}},
+			},
+			ServerInfo: ServerInfo{Name: "jackanalyzer"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		d := s.open(p.TextDocument.URI, p.TextDocument.Text)
+		if rerr := s.publish(d); rerr != nil {
+			return nil, rerr
+		}
+		return nil, s.publishStale(d.path)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		if _, ok := s.docs[p.TextDocument.URI]; ok && len(p.ContentChanges) > 0 {
+			d := s.open(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+			return nil, s.publishStale(d.path)
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		var p DidSaveTextDocumentParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if p.Text != nil {
+			d = s.open(d.uri, *p.Text)
+		}
+		if rerr := s.publish(d); rerr != nil {
+			return nil, rerr
+		}
+		return nil, s.publishStale(d.path)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if ok {
+			delete(s.docs, d.uri)
+			s.revert(d.path)
+		}
+		if rerr := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		}); rerr != nil || !ok {
+			return nil, rerr
+		}
+		return nil, s.publishStale(d.path)
+
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		return s.definition(p), nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		return s.hover(p), nil
+	case "textDocument/documentSymbol":
+		var p DocumentSymbolParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		return s.documentSymbol(p), nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if rerr := decode(m, &p); rerr != nil {
+			return nil, rerr
+		}
+		return s.completion(p), nil
+	}
+	return nil, &ResponseError{Code: CodeMethodNotFound, Message: "method not found: " + m.Method}
+}
+
+func decode(m *Message, v interface{}) *ResponseError {
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) notify(method string, params interface{}) *ResponseError {
+	if err := s.conn.Notify(method, params); err != nil {
+		return &ResponseError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return nil
+}
+
+// publish sends the diagnostics of d.
+func (s *Server) publish(d *document) *ResponseError {
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Diagnostics: s.diagnostics(d),
+	})
+}
+
+// publishStale sends the diagnostics of the open documents in the directory of path
+// whose diagnostics the change of the file of path invalidated,
+// in the order of the URIs.
+func (s *Server) publishStale(path string) *ResponseError {
+	ws := s.workspace(path)
+	var uris []string
+	for uri, d := range s.docs {
+		if filepath.Dir(d.path) == filepath.Dir(path) && ws.Stale(d.path) {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		if rerr := s.publish(s.docs[uri]); rerr != nil {
+			return rerr
+		}
+	}
+	return nil
+}
+
+// diagnostics returns the errors of d like 'jackanalyzer --vm':
+// the tokenize or syntax errors, or the semantic errors, the call errors
+// and the flow warnings if d is a valid class.
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	diags := []Diagnostic{}
+	for _, cd := range s.workspace(d.path).Diagnostics(d.path) {
+		sev := SeverityError
+		if cd.Severity == check.WARNING {
+			sev = SeverityWarning
+		}
+		diags = append(diags, d.diagnostic(cd.Pos, sev, cd.Msg))
+	}
+	return diags
+}
+
+// diagnostic returns Diagnostic ranging over the token at pos.
+func (d *document) diagnostic(pos token.Pos, sev DiagnosticSeverity, msg string) Diagnostic {
+	n := 1
+	if t := d.tokenAt(pos.Offset); t != nil && t.Offset == pos.Offset {
+		n = utf8.RuneCountInString(t.Lit)
+	}
+	return Diagnostic{
+		Range:    rangeOf(d.lines, pos, n),
+		Severity: sev,
+		Source:   "jackanalyzer",
+		Message:  msg,
+	}
+}
+
+// program returns the program of the directory of d
+// with the open documents in place of their files.
+func (s *Server) program(d *document) *program.Program {
+	return s.workspace(d.path).Program()
+}
+
+// workspace returns the workspace of the directory of path,
+// loading the files of the directory the first time.
+func (s *Server) workspace(path string) *workspace.Workspace {
+	dir := filepath.Dir(path)
+	ws, ok := s.workspaces[dir]
+	if !ok {
+		ws = workspace.New()
+		ws.Load(dir) // a new file may be in a directory not created yet
+		s.workspaces[dir] = ws
+	}
+	return ws
+}
+
+// open updates the document of uri to text.
+func (s *Server) open(uri, text string) *document {
+	path := uriToPath(uri)
+	ws := s.workspace(path)
+	ws.Update(path, []byte(text))
+	f, _ := ws.File(path)
+	d := &document{
+		uri:   uri,
+		path:  path,
+		text:  text,
+		lines: strings.Split(text, "\n"),
+		file:  f,
+	}
+	s.docs[uri] = d
+	return d
+}
+
+// revert updates the file of the closed document to the contents on the disk.
+func (s *Server) revert(path string) {
+	ws := s.workspace(path)
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		ws.Remove(path)
+		return
+	}
+	ws.Update(path, src)
+}
+
+// lines returns the lines of the file of path, open or on the disk.
+func (s *Server) lines(path string) []string {
+	for _, d := range s.docs {
+		if d.path == path {
+			return d.lines
+		}
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(src), "\n")
+}
+
+// tokenAt returns the token containing offset or ending at offset.
+// The token starting at offset wins over the token ending there.
+func (d *document) tokenAt(offset int) *token.Token {
+	i := sort.Search(len(d.file.Tokens), func(i int) bool {
+		return d.file.Tokens[i].Offset+len(d.file.Tokens[i].Lit) >= offset
+	})
+	if i+1 < len(d.file.Tokens) && d.file.Tokens[i+1].Offset == offset {
+		i++
+	}
+	if i < len(d.file.Tokens) && d.file.Tokens[i].Offset <= offset {
+		return d.file.Tokens[i]
+	}
+	return nil
+}
+
+// offset returns the byte offset of p in d.
+func (d *document) offset(p Position) int {
+	if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	off := 0
+	for _, l := range d.lines[:p.Line] {
+		off += len(l) + 1
+	}
+	line := d.lines[p.Line]
+	for i, units := 0, 0; i < len(line); {
+		if units >= p.Character {
+			return off + i
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		units += utf16Len(r)
+		i += size
+	}
+	return off + len(line)
+}
+
+// position returns Position of pos in lines.
+func position(lines []string, pos token.Pos) Position {
+	p := Position{Line: pos.Line - 1}
+	if p.Line < 0 {
+		// EOF of an empty document
+		return Position{}
+	}
+	if p.Line >= len(lines) {
+		return p
+	}
+	n := pos.Column - 1
+	for _, r := range lines[p.Line] {
+		if n == 0 {
+			break
+		}
+		p.Character += utf16Len(r)
+		n--
+	}
+	return p
+}
+
+// rangeOf returns Range of n runes from pos on a line.
+func rangeOf(lines []string, pos token.Pos, n int) Range {
+	end := pos
+	end.Column += n
+	return Range{Start: position(lines, pos), End: position(lines, end)}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// uriToPath returns the path of the file URI, or the URI itself otherwise.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+// pathToURI returns the file URI of path.
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,486 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client is an in-process JSON-RPC client of Serve.
+type client struct {
+	t      *testing.T
+	conn   *Conn
+	msgs   chan *Message
+	done   chan error // the result of Serve
+	nextID int
+}
+
+func newClient(t *testing.T) *client {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	c := &client{t: t, conn: NewConn(cr, cw), msgs: make(chan *Message, 100), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(sr, sw)
+		sw.Close()
+	}()
+	go func() {
+		for {
+			m, err := c.conn.Read()
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+			c.msgs <- m
+		}
+	}()
+	return c
+}
+
+// call sends the request and decodes the result into result.
+// It returns the error of the response.
+func (c *client) call(method string, params, result interface{}) *ResponseError {
+	c.t.Helper()
+	c.nextID++
+	if err := c.conn.Call(c.nextID, method, params); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		m := c.read()
+		if m.Method != "" {
+			continue // a notification
+		}
+		if string(m.ID) != strconv.Itoa(c.nextID) {
+			c.t.Fatalf("response id = %s, want %d", m.ID, c.nextID)
+		}
+		if m.Error != nil {
+			return m.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(m.Result, result); err != nil {
+				c.t.Fatalf("%s: %v: %s", method, err, m.Result)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.Notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics returns the next published diagnostics.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	m := c.read()
+	if m.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("message = %s %s, want publishDiagnostics", m.Method, m.Params)
+	}
+	var p PublishDiagnosticsParams
+	if err := json.Unmarshal(m.Params, &p); err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func (c *client) read() *Message {
+	c.t.Helper()
+	select {
+	case m, ok := <-c.msgs:
+		if !ok {
+			c.t.Fatal("connection closed")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout")
+	}
+	return nil
+}
+
+const ballSrc = `/** A ball. */
+class Ball {
+    field int x;
+
+    /** Creates a ball. */
+    constructor Ball new(int ax, int ay) {
+        let x = ax;
+        return this;
+    }
+
+    /** Moves the ball. */
+    method void move() {
+        return;
+    }
+
+    function int size() {
+        return 1;
+    }
+}
+`
+
+const mainSrc = `class Main {
+    static int count;
+
+    /** Runs the game. */
+    function void main() {
+        var Ball b;
+        let b = Ball.new(1, 2);
+        do b.move();
+        do Output.printInt(count);
+        do Main.missing();
+        return;
+    }
+}
+`
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "Ball.jack"), []byte(ballSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mainURI := pathToURI(filepath.Join(dir, "Main.jack"))
+	ballURI := pathToURI(filepath.Join(dir, "Ball.jack"))
+	doc := TextDocumentIdentifier{URI: mainURI}
+	at := func(line, char int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{TextDocument: doc, Position: Position{line, char}}
+	}
+	rng := func(line, char, n int) Range {
+		return Range{Position{line, char}, Position{line, char + n}}
+	}
+
+	c := newClient(t)
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != CodeServerNotInitialized {
+		t.Errorf("hover before initialize error = %v", err)
+	}
+	var init InitializeResult
+	if err := c.call("initialize", map[string]interface{}{}, &init); err != nil {
+		t.Fatal(err)
+	}
+	if !init.Capabilities.HoverProvider || init.Capabilities.TextDocumentSync.Change != TextDocumentSyncFull {
+		t.Errorf("initialize = %+v", init)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: mainURI, LanguageID: "jack", Version: 1, Text: mainSrc},
+		})
+		got := c.diagnostics()
+		want := PublishDiagnosticsParams{
+			URI: mainURI,
+			Diagnostics: []Diagnostic{{
+				Range:    rng(9, 11, 4),
+				Severity: SeverityError,
+				Source:   "jackanalyzer",
+				Message:  "undefined subroutine 'missing' in class Main",
+			}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("diagnostics = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		tests := []struct {
+			name string
+			pos  TextDocumentPositionParams
+			want *Location
+		}{
+			{"class", at(6, 17), &Location{ballURI, rng(1, 6, 4)}},
+			{"constructor", at(6, 22), &Location{ballURI, rng(5, 21, 3)}},
+			{"method of a variable", at(7, 14), &Location{ballURI, rng(11, 16, 4)}},
+			{"local", at(7, 11), &Location{mainURI, rng(5, 17, 1)}},
+			{"static", at(8, 28), &Location{mainURI, rng(1, 15, 5)}},
+			{"declaration", at(4, 19), &Location{mainURI, rng(4, 18, 4)}},
+			{"Jack OS", at(8, 20), nil},
+			{"keyword", at(4, 4), nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got *Location
+				if err := c.call("textDocument/definition", tt.pos, &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("definition = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		tests := []struct {
+			name string
+			pos  TextDocumentPositionParams
+			want string
+		}{
+			{"static", at(8, 27), "```jack\nstatic int count\n```"},
+			{"local", at(7, 11), "```jack\nvar Ball b\n```"},
+			{"method", at(7, 13), "```jack\nmethod void Ball.move()\n```\n\nMoves the ball."},
+			{"Jack OS", at(8, 20), "```jack\nfunction void Output.printInt(int i)\n```\n\n" +
+				"Displays i starting at the cursor location and advances the cursor appropriately."},
+			{"class", at(6, 16), "```jack\nclass Ball\n```\n\nA ball."},
+			{"own subroutine", at(4, 18), "```jack\nfunction void Main.main()\n```\n\nRuns the game."},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got *Hover
+				if err := c.call("textDocument/hover", tt.pos, &got); err != nil {
+					t.Fatal(err)
+				}
+				if got == nil || got.Contents.Value != tt.want {
+					t.Errorf("hover = %+v, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("documentSymbol", func(t *testing.T) {
+		var got []DocumentSymbol
+		if err := c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: doc}, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []DocumentSymbol{{
+			Name:           "Main",
+			Kind:           SymbolClass,
+			Range:          Range{Position{0, 6}, Position{12, 1}},
+			SelectionRange: rng(0, 6, 4),
+			Children: []DocumentSymbol{
+				{Name: "count", Detail: "static int", Kind: SymbolVariable, Range: rng(1, 15, 5), SelectionRange: rng(1, 15, 5)},
+				{Name: "main", Detail: "function void()", Kind: SymbolFunction, Range: Range{Position{4, 18}, Position{11, 5}}, SelectionRange: rng(4, 18, 4)},
+			},
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("documentSymbol = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		// the unfinished statements do not parse
+		src := strings.Replace(mainSrc, "        return;\n", "        do Math.\n        do b.m\n        do Ball.\n        return;\n", 1)
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   doc,
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: src}},
+		})
+		labels := func(l CompletionList) []string {
+			var s []string
+			for _, item := range l.Items {
+				s = append(s, item.Label)
+			}
+			return s
+		}
+		tests := []struct {
+			name string
+			pos  TextDocumentPositionParams
+			want []string
+		}{
+			{"Jack OS class", at(10, 16), []string{"abs", "divide", "init", "max", "min", "multiply", "sqrt"}},
+			{"variable", at(11, 14), []string{"move"}},
+			{"class", at(12, 16), []string{"new", "size"}},
+			{"no dot", at(10, 14), nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got CompletionList
+				if err := c.call("textDocument/completion", tt.pos, &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(labels(got), tt.want) {
+					t.Errorf("completion = %v, want %v", labels(got), tt.want)
+				}
+			})
+		}
+
+		var got CompletionList
+		if err := c.call("textDocument/completion", at(11, 14), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := CompletionItem{
+			Label:         "move",
+			Kind:          CompletionMethod,
+			Detail:        "method void Ball.move()",
+			Documentation: &MarkupContent{Kind: "markdown", Value: "Moves the ball."},
+		}
+		if len(got.Items) != 1 || !reflect.DeepEqual(got.Items[0], want) {
+			t.Errorf("completion = %+v, want %+v", got.Items, want)
+		}
+	})
+
+	t.Run("save and close", func(t *testing.T) {
+		fixed := strings.Replace(mainSrc, "        do Main.missing();\n", "", 1)
+		c.notify("textDocument/didSave", DidSaveTextDocumentParams{TextDocument: doc, Text: &fixed})
+		if got := c.diagnostics(); len(got.Diagnostics) != 0 {
+			t.Errorf("diagnostics after save = %+v, want none", got.Diagnostics)
+		}
+		c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: doc})
+		if got := c.diagnostics(); got.URI != mainURI || got.Diagnostics == nil || len(got.Diagnostics) != 0 {
+			t.Errorf("diagnostics after close = %+v, want empty", got)
+		}
+	})
+
+	t.Run("open documents", func(t *testing.T) {
+		// the unsaved Ball changes the diagnostics of Main
+		c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: ballURI, LanguageID: "jack", Version: 1, Text: strings.Replace(ballSrc, "move()", "move(int dx)", 1)},
+		})
+		if got := c.diagnostics(); len(got.Diagnostics) != 0 {
+			t.Errorf("diagnostics of Ball = %+v, want none", got.Diagnostics)
+		}
+		c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: mainURI, LanguageID: "jack", Version: 1, Text: mainSrc},
+		})
+		got := c.diagnostics()
+		var msgs []string
+		for _, d := range got.Diagnostics {
+			msgs = append(msgs, d.Message)
+		}
+		want := []string{"'Ball.move' takes 1 arguments, got 0", "undefined subroutine 'missing' in class Main"}
+		if !reflect.DeepEqual(msgs, want) {
+			t.Errorf("diagnostics of Main = %q, want %q", msgs, want)
+		}
+	})
+
+	t.Run("invalidated documents", func(t *testing.T) {
+		// Ball and Main are open: the change of the signature of Ball.move
+		// republishes the diagnostics of Main
+		tests := []struct {
+			name string
+			ball string
+			want []string
+		}{
+			{"remove the parameter", ballSrc, []string{"undefined subroutine 'missing' in class Main"}},
+			{"add the parameter", strings.Replace(ballSrc, "move()", "move(int dx)", 1), []string{"'Ball.move' takes 1 arguments, got 0", "undefined subroutine 'missing' in class Main"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+					TextDocument:   TextDocumentIdentifier{URI: ballURI},
+					ContentChanges: []TextDocumentContentChangeEvent{{Text: tt.ball}},
+				})
+				got := c.diagnostics()
+				if got.URI != mainURI {
+					t.Fatalf("diagnostics of %s, want %s", got.URI, mainURI)
+				}
+				var msgs []string
+				for _, d := range got.Diagnostics {
+					msgs = append(msgs, d.Message)
+				}
+				if !reflect.DeepEqual(msgs, tt.want) {
+					t.Errorf("diagnostics of Main = %q, want %q", msgs, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("no class", func(t *testing.T) {
+		otherURI := pathToURI(filepath.Join(dir, "Other.jack"))
+		other := TextDocumentIdentifier{URI: otherURI}
+		tests := []struct {
+			name string
+			src  string
+			want Diagnostic
+		}{
+			{"empty", "", Diagnostic{Range: rng(0, 0, 0), Message: "expected [class], got EOF"}},
+			{"no class header", "cla", Diagnostic{Range: rng(0, 0, 3), Message: "expected [class], got identifier 'cla'"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+					TextDocument: TextDocumentItem{URI: otherURI, LanguageID: "jack", Version: 1, Text: tt.src},
+				})
+				got := c.diagnostics()
+				tt.want.Severity, tt.want.Source = SeverityError, "jackanalyzer"
+				if len(got.Diagnostics) != 1 || !reflect.DeepEqual(got.Diagnostics[0], tt.want) {
+					t.Errorf("diagnostics = %+v, want %+v", got.Diagnostics, tt.want)
+				}
+				pos := TextDocumentPositionParams{TextDocument: other, Position: Position{0, 1}}
+				for _, method := range []string{"textDocument/definition", "textDocument/hover", "textDocument/completion"} {
+					if err := c.call(method, pos, nil); err != nil {
+						t.Errorf("%s error = %v", method, err)
+					}
+				}
+				var syms []DocumentSymbol
+				if err := c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: other}, &syms); err != nil || len(syms) != 0 {
+					t.Errorf("documentSymbol = %+v, %v, want none", syms, err)
+				}
+				c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: other})
+				c.diagnostics()
+			})
+		}
+	})
+
+	t.Run("non-ASCII", func(t *testing.T) {
+		// 'é' is a UTF-16 code unit and '𝄞' is two
+		otherURI := pathToURI(filepath.Join(dir, "Other.jack"))
+		src := "class Other { function void f() { do Output.printString(\"é𝄞\"); let x = 1; return; } }"
+		c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: otherURI, LanguageID: "jack", Version: 1, Text: src},
+		})
+		got := c.diagnostics()
+		want := Diagnostic{Range: rng(0, 68, 1), Severity: SeverityError, Source: "jackanalyzer", Message: "undeclared variable 'x'"}
+		if len(got.Diagnostics) == 0 || !reflect.DeepEqual(got.Diagnostics[0], want) {
+			t.Errorf("diagnostics = %+v, want %+v", got.Diagnostics, want)
+		}
+		c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: otherURI}})
+		c.diagnostics()
+	})
+
+	if err := c.call("no/such/method", nil, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Errorf("unknown method error = %v", err)
+	}
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != CodeInvalidRequest {
+		t.Errorf("hover after shutdown error = %v", err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
+
+func TestServe_exitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Errorf("Serve() = %v, want %v", err, ErrExitWithoutShutdown)
+	}
+}
+
+func TestConn_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"message", "Content-Length: 17\r\n\r\n{\"method\":\"exit\"}", "exit", false},
+		{"extra header", "Content-Type: application/vscode-jsonrpc\r\ncontent-length: 17\r\n\r\n{\"method\":\"exit\"}", "exit", false},
+		{"no length", "\r\n{}", "", true},
+		{"short body", "Content-Length: 17\r\n\r\n{}", "", true},
+		{"invalid json", "Content-Length: 2\r\n\r\n{]", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewConn(strings.NewReader(tt.in), ioutil.Discard).Read()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && m.Method != tt.want {
+				t.Errorf("Read() method = %q, want %q", m.Method, tt.want)
+			}
+		})
+	}
+	if _, err := NewConn(strings.NewReader(""), ioutil.Discard).Read(); err != io.EOF {
+		t.Errorf("Read() at EOF error = %v, want io.EOF", err)
+	}
+}
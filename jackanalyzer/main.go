@@ -1,7 +1,381 @@
 package main
 
-import "fmt"
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"jackanalyzer/check"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/codegen"
+	"jackanalyzer/element"
+	"jackanalyzer/flow"
+	"jackanalyzer/program"
+	"jackanalyzer/tokenizer"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: jackanalyzer [--tokens | --vm | --unused] [--strict-types | --strict-types-error] <file.jack|dir>
+       jackanalyzer fmt [-w] [-d] [--split-decls] <file.jack|dir>
+       jackanalyzer doc [-o dir] [--markdown] [--os] <file.jack|dir>
+       jackanalyzer lsp
+
+JackAnalyzer writes Xxx.xml (parse tree) and XxxT.xml (tokens)
+next to every Xxx.jack source. Except with --tokens, it checks the
+declarations, the calls and the control flow first, and writes nothing
+for a file with an error.
+
+flags:
+  --tokens              write only XxxT.xml
+  --vm                  write only Xxx.vm (VM code)
+  --unused              print the unused fields, statics, parameters and
+                        subroutines of the program as JSON
+  --strict-types        report the type mismatches as warnings
+  --strict-types-error  report the type mismatches as errors
+
+fmt prints every Xxx.jack in the canonical format.
+
+fmt flags:
+  -w                    write the result to Xxx.jack instead of stdout
+  -d                    print the diff instead of the formatted source
+  --split-decls         declare a variable per line
+
+doc writes the API reference of every Xxx.jack from its /** */ comments:
+index.html and Xxx.html.
+
+doc flags:
+  -o dir                write the pages into dir (default doc)
+  --markdown            write index.md and Xxx.md instead of HTML
+  --os                  document the Jack OS classes not implemented
+                        by the program too
+
+lsp runs the Language Server Protocol server on stdin and stdout.
+`
+
+// options is the flags of the command.
+type options struct {
+	tokensOnly  bool
+	vm          bool
+	unused      bool
+	strictTypes bool
+	typeErrors  bool // escalate the type mismatches to errors
+}
 
 func main() {
-	fmt.Println("hello world")
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run is the entry point of the command. It returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "fmt" {
+		return runFmt(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "doc" {
+		return runDoc(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "lsp" {
+		return runLSP(args[1:], os.Stdin, stdout, stderr)
+	}
+	fs := flag.NewFlagSet("jackanalyzer", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	var opts options
+	fs.BoolVar(&opts.tokensOnly, "tokens", false, "write only XxxT.xml")
+	fs.BoolVar(&opts.vm, "vm", false, "write only Xxx.vm")
+	fs.BoolVar(&opts.unused, "unused", false, "print the unused declarations as JSON")
+	fs.BoolVar(&opts.strictTypes, "strict-types", false, "report the type mismatches as warnings")
+	fs.BoolVar(&opts.typeErrors, "strict-types-error", false, "report the type mismatches as errors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	modes := 0
+	for _, m := range []bool{opts.tokensOnly, opts.vm, opts.unused} {
+		if m {
+			modes++
+		}
+	}
+	if fs.NArg() != 1 || modes > 1 {
+		fs.Usage()
+		return 2
+	}
+	if opts.unused {
+		return reportUnused(fs.Arg(0), stdout, stderr)
+	}
+	if opts.typeErrors {
+		opts.strictTypes = true
+	}
+
+	files, err := jackFiles(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(stderr, "jackanalyzer: no .jack files in %s\n", fs.Arg(0))
+		return 1
+	}
+
+	var idx program.Index
+	if !opts.tokensOnly {
+		idx, err = loadIndex(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+			return 1
+		}
+	}
+
+	failed := 0
+	for _, f := range files {
+		var err error
+		if opts.vm {
+			err = compileFile(f, idx, opts, stderr)
+		} else {
+			err = analyzeFile(f, idx, opts, stderr)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintln(stderr, errorMessage(f, err))
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", f)
+	}
+	if failed != 0 {
+		fmt.Fprintf(stderr, "jackanalyzer: %d of %d files failed\n", failed, len(files))
+		return 1
+	}
+	return 0
+}
+
+// errorMessage returns "path:line:column: msg" for the positioned error,
+// "path: msg" otherwise.
+// cmplengn.ErrorList and check.Diagnostics are a line per error.
+func errorMessage(path string, err error) string {
+	var te *tokenizer.Error
+	if errors.As(err, &te) {
+		return fmt.Sprintf("%s:%v", path, te)
+	}
+	var el cmplengn.ErrorList
+	if errors.As(err, &el) {
+		lines := make([]string, len(el))
+		for i, e := range el {
+			lines[i] = fmt.Sprintf("%s:%v", path, e)
+		}
+		return strings.Join(lines, "\n")
+	}
+	var dl check.Diagnostics
+	if errors.As(err, &dl) {
+		lines := make([]string, len(dl))
+		for i, d := range dl {
+			lines[i] = fmt.Sprintf("%s:%v", path, d)
+		}
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("%s: %v", path, err)
+}
+
+// jackFiles returns path itself when it is a .jack file,
+// or every .jack file directly under path when it is a directory.
+func jackFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		if filepath.Ext(path) != ".jack" {
+			return nil, fmt.Errorf("%s is not a .jack file", path)
+		}
+		return []string{path}, nil
+	}
+
+	fis, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".jack" {
+			continue
+		}
+		files = append(files, filepath.Join(path, fi.Name()))
+	}
+	return files, nil
+}
+
+// loadProgram loads the program containing path:
+// every .jack file of the directory path, or of the directory of the file path.
+func loadProgram(path string) (*program.Program, error) {
+	dir := path
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	return program.Load(dir)
+}
+
+// loadIndex returns the class/subroutine index of the program containing path.
+func loadIndex(path string) (program.Index, error) {
+	p, err := loadProgram(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Index, nil
+}
+
+// reportUnused prints the unused declarations of the program containing path
+// to stdout as a JSON array. It returns the exit code.
+// The files with errors are reported to stderr, and then no declaration is reported as unused.
+func reportUnused(path string, stdout, stderr io.Writer) int {
+	p, err := loadProgram(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	code := 0
+	for _, f := range p.Files {
+		if f.Err != nil {
+			code = 1
+			fmt.Fprintln(stderr, errorMessage(f.Path, f.Err))
+		}
+	}
+	unused := p.Unused()
+	if unused == nil {
+		unused = []program.Unused{}
+	}
+	e := json.NewEncoder(stdout)
+	e.SetIndent("", "  ")
+	if err := e.Encode(unused); err != nil {
+		fmt.Fprintf(stderr, "jackanalyzer: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// analyzeFile writes Xxx.xml and XxxT.xml for Xxx.jack.
+// It writes only XxxT.xml if opts.tokensOnly is true.
+// Otherwise it writes neither file if the syntax or checkClass reports an error.
+//
+// Xxx.xml is the XML of the class returned by cmplengn.Parse, not the output of
+// CompilationEngine, so that every syntax error of the file is reported.
+func analyzeFile(path string, idx program.Index, opts options, stderr io.Writer) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	head, err := tokenizer.New(src).Tokenize()
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	writeTokens := func() error {
+		return writeFile(base+"T.xml", func(w io.Writer) error {
+			return tokenizer.WriteXML(w, head)
+		})
+	}
+	if opts.tokensOnly {
+		return writeTokens()
+	}
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		return err
+	}
+	if err := checkClass(path, cl, idx, opts, stderr); err != nil {
+		return err
+	}
+	if err := writeTokens(); err != nil {
+		return err
+	}
+	return writeFile(base+".xml", func(w io.Writer) error {
+		e := cmplengn.NewEncoder(w)
+		if err := e.Encode(cl); err != nil {
+			return err
+		}
+		if err := e.Flush(); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+}
+
+// compileFile writes Xxx.vm for Xxx.jack.
+// It writes nothing if checkClass reports an error.
+func compileFile(path string, idx program.Index, opts options, stderr io.Writer) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	head, err := tokenizer.New(src).Tokenize()
+	if err != nil {
+		return err
+	}
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		return err
+	}
+	if err := checkClass(path, cl, idx, opts, stderr); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	return writeFile(base+".vm", func(w io.Writer) error {
+		return codegen.Generate(w, cl)
+	})
+}
+
+// checkClass runs check.Check, program.CheckCalls against idx, check.CheckTypes
+// if opts.strictTypes is true, flow.Check and flow.CheckAssignments on cl.
+// It returns the diagnostics of every pass in the order of the source as the error
+// if one of them is an error, otherwise prints them to stderr as warnings.
+func checkClass(path string, cl *element.Class, idx program.Index, opts options, stderr io.Writer) error {
+	var diags check.Diagnostics
+	diags = append(diags, check.Check(path, cl)...)
+	diags = append(diags, program.CheckCalls(cl, idx)...)
+	if opts.strictTypes {
+		diags = append(diags, check.CheckTypes(cl, check.TypeConfig{Escalate: opts.typeErrors})...)
+	}
+	diags = append(diags, flow.Check(cl)...)
+	diags = append(diags, flow.CheckAssignments(cl)...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	})
+	return warn(path, diags, stderr)
+}
+
+// warn returns diags as the error if they have ERROR,
+// otherwise prints them to stderr as warnings.
+func warn(path string, diags check.Diagnostics, stderr io.Writer) error {
+	if err := diags.Err(); err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fmt.Fprintf(stderr, "%s:%v\n", path, d)
+	}
+	return nil
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeJack(t *testing.T, dir, name, src string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		arg        func(dir string) string
+		want       int
+		wantOutput []string
+	}{
+		{
+			"directory",
+			map[string]string{
+				"Main.jack": "class Main {}",
+				"Foo.jack":  "class Foo {}",
+				"bar.txt":   "not jack",
+			},
+			func(dir string) string { return dir },
+			0,
+			[]string{"Main.xml", "MainT.xml", "Foo.xml", "FooT.xml"},
+		},
+		{
+			"single file",
+			map[string]string{
+				"Main.jack": "class Main {}",
+			},
+			func(dir string) string { return filepath.Join(dir, "Main.jack") },
+			0,
+			[]string{"Main.xml", "MainT.xml"},
+		},
+		{
+			"syntax error",
+			map[string]string{
+				"Main.jack": "class Main {}",
+				"Bad.jack":  "class Bad {",
+			},
+			func(dir string) string { return dir },
+			1,
+			[]string{"Main.xml", "MainT.xml"},
+		},
+		{
+			"not a jack file",
+			map[string]string{
+				"bar.txt": "not jack",
+			},
+			func(dir string) string { return filepath.Join(dir, "bar.txt") },
+			1,
+			nil,
+		},
+		{
+			"empty directory",
+			nil,
+			func(dir string) string { return dir },
+			1,
+			nil,
+		},
+		{
+			"not exist",
+			nil,
+			func(dir string) string { return filepath.Join(dir, "nothing") },
+			1,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, src := range tt.files {
+				writeJack(t, dir, name, src)
+			}
+			var stdout, stderr bytes.Buffer
+			if got := run([]string{tt.arg(dir)}, &stdout, &stderr); got != tt.want {
+				t.Errorf("run() = %v, want %v. stderr = %s", got, tt.want, stderr.String())
+			}
+			for _, name := range tt.wantOutput {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("run() did not write %s: %v", name, err)
+				}
+			}
+			outs, _ := filepath.Glob(filepath.Join(dir, "*.xml"))
+			if len(outs) != len(tt.wantOutput) {
+				t.Errorf("run() wrote %v, want %v", outs, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func Test_run_positionedError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  \"hoge")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{dir}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() = %v, want 1", got)
+	}
+	want := path + ":2:3: unterminated string constant\n"
+	if !strings.HasPrefix(stderr.String(), want) {
+		t.Errorf("run() stderr = %q, want prefix %q", stderr.String(), want)
+	}
+}
+
+// copyTestdata copies the .jack files of cmplengn/testdata/name into a temporary directory.
+func copyTestdata(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := filepath.Glob(filepath.Join("cmplengn", "testdata", name, "*.jack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		src, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeJack(t, dir, filepath.Base(f), string(src))
+	}
+	return dir
+}
+
+// Test_run_compare compares the output with the compare files in cmplengn/testdata
+// except for the line endings: the output ends the lines with LF
+// and the compare files of nand2tetris end them with CRLF.
+func Test_run_compare(t *testing.T) {
+	for _, name := range []string{"ArrayTest", "Square", "ExpressionLessSquare"} {
+		for _, tokensOnly := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s tokens=%v", name, tokensOnly), func(t *testing.T) {
+				dir := copyTestdata(t, name)
+				args := []string{dir}
+				if tokensOnly {
+					args = []string{"--tokens", dir}
+				}
+				var stdout, stderr bytes.Buffer
+				if got := run(args, &stdout, &stderr); got != 0 {
+					t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+				}
+				outs, _ := filepath.Glob(filepath.Join(dir, "*.xml"))
+				if len(outs) == 0 {
+					t.Fatal("run() wrote no .xml")
+				}
+				for _, out := range outs {
+					isTokens := strings.HasSuffix(out, "T.xml")
+					if tokensOnly && !isTokens {
+						t.Errorf("run() wrote %s in tokens mode", out)
+						continue
+					}
+					got, _ := ioutil.ReadFile(out)
+					want, err := ioutil.ReadFile(filepath.Join("cmplengn", "testdata", name, filepath.Base(out)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, bytes.Replace(want, []byte("\r\n"), []byte("\n"), -1)) {
+						t.Errorf("run() %s differs from the compare file", filepath.Base(out))
+					}
+				}
+			})
+		}
+	}
+}
+
+func Test_run_usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if got := run(nil, &stdout, &stderr); got != 2 {
+		t.Errorf("run() = %v, want 2", got)
+	}
+	if !strings.HasPrefix(stderr.String(), "usage:") {
+		t.Errorf("run() stderr = %q", stderr.String())
+	}
+}
+
+func Test_run_vm(t *testing.T) {
+	dir := t.TempDir()
+	writeJack(t, dir, "Main.jack", "class Main { function void main() { do Output.printInt(1 + 2); return; } }")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--vm", dir}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "Main.vm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `function Main.main 0
+push constant 1
+push constant 2
+add
+call Output.printInt 1
+pop temp 0
+push constant 0
+return
+`
+	if string(got) != want {
+		t.Errorf("Main.vm = %v, want %v", string(got), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Main.xml")); !os.IsNotExist(err) {
+		t.Errorf("run() --vm wrote Main.xml: %v", err)
+	}
+}
+
+func Test_run_exclusiveFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--tokens", "--vm", t.TempDir()}, &stdout, &stderr); got != 2 {
+		t.Errorf("run() = %v, want 2", got)
+	}
+}
+
+func Test_run_syntaxErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  field int x\n  function void main() {\n    let x = ;\n    return;\n  }\n}\n")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{dir}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() = %v, want 1", got)
+	}
+	want := path + ":3:3: expected ';', got keyword 'function'\n" +
+		path + ":4:13: expected term, got symbol ';'\n" +
+		"jackanalyzer: 1 of 1 files failed\n"
+	if got := stderr.String(); got != want {
+		t.Errorf("run() stderr = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Main.xml")); !os.IsNotExist(err) {
+		t.Errorf("run() wrote Main.xml: %v", err)
+	}
+}
+
+func Test_run_check(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		outs []string // not written
+	}{
+		{"xml", nil, []string{"Main.xml", "MainT.xml"}},
+		{"vm", []string{"--vm"}, []string{"Main.vm"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeJack(t, dir, "Main.jack", "class Foo {\n  function void main() {\n    let x = 1;\n    do Foo.draw();\n    return;\n    return;\n  }\n}\n")
+			var stdout, stderr bytes.Buffer
+			if got := run(append(tt.args, dir), &stdout, &stderr); got != 1 {
+				t.Errorf("run() = %v, want 1", got)
+			}
+			// the errors and the warnings of every check at once
+			want := path + ":1:7: class name 'Foo' differs from file name 'Main.jack'\n" +
+				path + ":3:9: undeclared variable 'x'\n" +
+				path + ":4:8: undefined subroutine 'draw' in class Foo\n" +
+				path + ":6:5: warning: statement unreachable after return\n"
+			if got := stderr.String(); !strings.HasPrefix(got, want) {
+				t.Errorf("run() stderr = %q, want prefix %q", got, want)
+			}
+			for _, out := range tt.outs {
+				if _, err := os.Stat(filepath.Join(dir, out)); !os.IsNotExist(err) {
+					t.Errorf("run() wrote %s: %v", out, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_run_vm_calls(t *testing.T) {
+	dir := t.TempDir()
+	writeJack(t, dir, "Point.jack", "class Point {\n  function Point origin() {\n    return null;\n  }\n}\n")
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  function void main() {\n    do Point.origin(1);\n    do Point.new();\n    return;\n  }\n}\n")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--vm", path}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() = %v, want 1", got)
+	}
+	want := path + ":3:8: 'Point.origin' takes 0 arguments, got 1\n" +
+		path + ":4:8: undefined subroutine 'new' in class Point\n"
+	if got := stderr.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("run() stderr = %q, want prefix %q", got, want)
+	}
+}
+
+func Test_run_vm_flow(t *testing.T) {
+	dir := t.TempDir()
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  function void main() {\n    return;\n    do Sys.halt();\n  }\n  function int f() {\n    do Main.main();\n  }\n}\n")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--vm", dir}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() = %v, want 1", got)
+	}
+	want := path + ":4:5: warning: statement unreachable after return\n" +
+		path + ":8:3: subroutine 'f' may fall off the end without return\n"
+	if got := stderr.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("run() stderr = %q, want prefix %q", got, want)
+	}
+
+	writeJack(t, dir, "Main.jack", "class Main {\n  function void main() {\n    return;\n    do Sys.halt();\n  }\n}\n")
+	stdout.Reset()
+	stderr.Reset()
+	if got := run([]string{"--vm", dir}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	want = path + ":4:5: warning: statement unreachable after return\n"
+	if got := stderr.String(); got != want {
+		t.Errorf("run() stderr = %q, want %q", got, want)
+	}
+}
+
+func Test_run_vm_assignments(t *testing.T) {
+	dir := t.TempDir()
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  function void main() {\n    var Point p;\n    do p.draw();\n    return;\n  }\n}\n")
+	writeJack(t, dir, "Point.jack", "class Point {\n  method void draw() {\n    return;\n  }\n}\n")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--vm", path}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	want := path + ":4:8: warning: local variable 'p' may be used before assignment\n"
+	if got := stderr.String(); got != want {
+		t.Errorf("run() stderr = %q, want %q", got, want)
+	}
+}
+
+func Test_run_unused(t *testing.T) {
+	dir := t.TempDir()
+	path := writeJack(t, dir, "Main.jack", "class Main {\n  field int x;\n  function void main() {\n    return;\n  }\n}\n")
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"--unused", dir}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("run() stdout = %s: %v", stdout.String(), err)
+	}
+	want := []map[string]interface{}{
+		{"kind": "field", "class": "Main", "name": "x", "file": path, "line": 2.0, "column": 13.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("run() stdout = %v, want %v", got, want)
+	}
+
+	stdout.Reset()
+	if got := run([]string{"--unused", "--vm", dir}, &stdout, &stderr); got != 2 {
+		t.Errorf("run() = %v, want 2", got)
+	}
+}
+
+func Test_run_fmt(t *testing.T) {
+	dir := t.TempDir()
+	src := "class Main {\nfunction void main() { return; }\n}\n"
+	want := "class Main {\n    function void main() {\n        return;\n    }\n}\n"
+	path := writeJack(t, dir, "Main.jack", src)
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"fmt", path}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	if stdout.String() != want {
+		t.Errorf("run() stdout = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if got := run([]string{"fmt", "-d", dir}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() -d = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	wantDiff := "--- " + path + ".orig\n+++ " + path + "\n@@ -1,3 +1,5 @@\n class Main {\n" +
+		"-function void main() { return; }\n+    function void main() {\n+        return;\n+    }\n }\n"
+	if stdout.String() != wantDiff {
+		t.Errorf("run() -d stdout = %q, want %q", stdout.String(), wantDiff)
+	}
+
+	stdout.Reset()
+	if got := run([]string{"fmt", "-w", dir}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() -w = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("run() -w stdout = %q, want empty", stdout.String())
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Main.jack = %q, want %q", got, want)
+	}
+
+	writeJack(t, dir, "Main.jack", "class Main {")
+	if got := run([]string{"fmt", "-w", dir}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() -w = %v, want 1 for syntax error", got)
+	}
+}
+
+func Test_run_doc(t *testing.T) {
+	dir := t.TempDir()
+	writeJack(t, dir, "Main.jack", "/** The entry. */\nclass Main {\n  /** Runs. */\n  function void main() { return; }\n}\n")
+	writeJack(t, dir, "Broken.jack", "class Broken {")
+	out := filepath.Join(dir, "out")
+
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"doc", "--markdown", "-o", out, dir}, &stdout, &stderr); got != 1 {
+		t.Errorf("run() = %v, want 1 for syntax error", got)
+	}
+	if !strings.Contains(stderr.String(), "Broken.jack:") {
+		t.Errorf("run() stderr = %q, want the error of Broken.jack", stderr.String())
+	}
+	got, err := ioutil.ReadFile(filepath.Join(out, "Main.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Index](index.md)\n\n# class Main\n\nThe entry.\n\n## Subroutines\n\n<a id=\"main\"></a>\n\n### function void main()\n\nRuns.\n\n"
+	if string(got) != want {
+		t.Errorf("Main.md = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(out, "Broken.md")); !os.IsNotExist(err) {
+		t.Errorf("Broken.md is written: %v", err)
+	}
+
+	stderr.Reset()
+	mainPath := filepath.Join(dir, "Main.jack")
+	if got := run([]string{"doc", "--os", "-o", out, mainPath}, &stdout, &stderr); got != 0 {
+		t.Fatalf("run() --os = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	index, err := ioutil.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<a href="Main.html">Main</a></td><td>The entry.`, `<a href="Math.html">Math</a>`} {
+		if !strings.Contains(string(index), s) {
+			t.Errorf("index.html does not contain %q:\n%s", s, index)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "Sys.html")); err != nil {
+		t.Errorf("Sys.html: %v", err)
+	}
+}
+
+func Test_runLSP(t *testing.T) {
+	var in bytes.Buffer
+	for _, m := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var stdout, stderr bytes.Buffer
+	if got := runLSP(nil, &in, &stdout, &stderr); got != 0 {
+		t.Fatalf("runLSP() = %v, want 0. stderr = %s", got, stderr.String())
+	}
+	for _, s := range []string{`"id":1,"result":{"capabilities":`, `{"jsonrpc":"2.0","id":2,"result":null}`} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Errorf("runLSP() stdout = %s, want %s", stdout.String(), s)
+		}
+	}
+
+	if got := runLSP(nil, strings.NewReader(""), &stdout, &stderr); got != 1 {
+		t.Errorf("runLSP() = %v, want 1 at EOF without exit", got)
+	}
+}
+
+func Test_run_strictTypes(t *testing.T) {
+	src := "class Main {\n  function void main() {\n    var int x;\n    let x = true;\n    do Output.printInt(x);\n    return;\n  }\n}\n"
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStderr string
+	}{
+		{"off", nil, 0, ""},
+		{"warning", []string{"--strict-types"}, 0, ":4:9: warning: cannot assign boolean to int variable 'x'\n"},
+		{"error", []string{"--strict-types-error"}, 1, ":4:9: cannot assign boolean to int variable 'x'\n"},
+		{"vm error", []string{"--vm", "--strict-types-error"}, 1, ":4:9: cannot assign boolean to int variable 'x'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeJack(t, dir, "Main.jack", src)
+			var stdout, stderr bytes.Buffer
+			if got := run(append(tt.args, dir), &stdout, &stderr); got != tt.want {
+				t.Errorf("run() = %v, want %v", got, tt.want)
+			}
+			want := ""
+			if tt.wantStderr != "" {
+				want = path + tt.wantStderr
+			}
+			if got := stderr.String(); !strings.HasPrefix(got, want) || (want == "" && got != "") {
+				t.Errorf("run() stderr = %q, want prefix %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package program
+
+import (
+	"fmt"
+	"jackanalyzer/check"
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"sort"
+)
+
+// callChecker resolves the subroutine calls of a class.
+type callChecker struct {
+	idx   Index
+	cl    *element.Class
+	st    *symboltable.SymbolTable
+	sd    *element.SubroutineDec // current subroutine
+	diags check.Diagnostics
+}
+
+// CheckCalls reports the subroutine calls of cl which do not resolve in idx:
+// the unknown classes and subroutines, the calls of the wrong kind and the wrong number of arguments.
+//
+//  subroutineName(...)           -> method of this class, called from a method or constructor
+//  varName.subroutineName(...)   -> method of the class of varName
+//  className.subroutineName(...) -> function or constructor of className
+//
+// The method call on a variable of int, char or boolean is left to check.CheckTypes.
+func CheckCalls(cl *element.Class, idx Index) check.Diagnostics {
+	c := &callChecker{
+		idx: idx,
+		cl:  cl,
+		st:  symboltable.NewClass(cl),
+	}
+	for _, sd := range cl.Sds {
+		c.sd = sd
+		c.st.StartSubroutineDec(sd)
+		c.checkStatements(sd.Sb.Stmts)
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		return c.diags[i].Pos.Offset < c.diags[j].Pos.Offset
+	})
+	return c.diags
+}
+
+func (c *callChecker) checkStatements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			if s.Lexp != nil {
+				c.checkExpression(s.Lexp)
+			}
+			c.checkExpression(&s.Rexp)
+		case *element.IfStatement:
+			c.checkExpression(&s.LExp)
+			c.checkStatements(s.Stmts)
+			c.checkStatements(s.EStmts)
+		case *element.WhileStatement:
+			c.checkExpression(&s.Exp)
+			c.checkStatements(s.Stmts)
+		case *element.DoStatement:
+			c.checkSubroutineCall(s.Sub)
+		case *element.ReturnStatement:
+			if s.Exp != nil {
+				c.checkExpression(s.Exp)
+			}
+		}
+	}
+}
+
+func (c *callChecker) checkExpression(exp *element.Expression) {
+	c.checkTerm(exp.Term)
+	for _, v := range exp.Next {
+		c.checkTerm(v.Term)
+	}
+}
+
+func (c *callChecker) checkTerm(term element.Term) {
+	switch t := term.(type) {
+	case *element.CallIndex:
+		c.checkExpression(&t.Exp)
+	case *element.SubroutineCall:
+		c.checkSubroutineCall(t)
+	case *element.Args:
+		c.checkExpression(&t.Exp)
+	case *element.UopTerm:
+		c.checkTerm(t.Term)
+	}
+}
+
+// checkSubroutineCall resolves sbc and checks its arguments.
+func (c *callChecker) checkSubroutineCall(sbc *element.SubroutineCall) {
+	for i := range sbc.ExpL {
+		c.checkExpression(&sbc.ExpL[i])
+	}
+
+	cn, sn := string(c.cl.Cn), string(sbc.Sn)
+	switch {
+	case sbc.Dot == "":
+		sub, ok := c.idx.Lookup(cn, sn)
+		if !ok {
+			c.errorf(sbc.Pos, "undefined subroutine '%s' in class %s", sn, cn)
+			return
+		}
+		if sub.Kind != "method" {
+			c.errorf(sbc.Pos, "%s '%s.%s' called as a method", sub.Kind, cn, sn)
+			return
+		}
+		if string(c.sd.Modi) == "function" {
+			c.errorf(sbc.Pos, "method '%s.%s' called from function '%s'", cn, sn, string(c.sd.Sn))
+			return
+		}
+		c.checkArgs(sbc, sub)
+	default:
+		name := string(sbc.Name)
+		if s, ok := c.st.Lookup(name); ok {
+			if isPrimitive(s.Type) {
+				return
+			}
+			sub, ok := c.lookup(sbc, s.Type)
+			if !ok {
+				return
+			}
+			if sub.Kind != "method" {
+				c.errorf(sbc.Pos, "%s '%s.%s' called as a method on '%s'", sub.Kind, s.Type, sn, name)
+				return
+			}
+			c.checkArgs(sbc, sub)
+			return
+		}
+		sub, ok := c.lookup(sbc, name)
+		if !ok {
+			return
+		}
+		if sub.Kind == "method" {
+			c.errorf(sbc.Pos, "method '%s.%s' called as a function", name, sn)
+			return
+		}
+		c.checkArgs(sbc, sub)
+	}
+}
+
+// lookup returns the subroutine cn.sbc.Sn, reporting the unknown class or subroutine.
+func (c *callChecker) lookup(sbc *element.SubroutineCall, cn string) (*Subroutine, bool) {
+	if !c.idx.HasClass(cn) {
+		c.errorf(sbc.Pos, "undefined class '%s'", cn)
+		return nil, false
+	}
+	sub, ok := c.idx.Lookup(cn, string(sbc.Sn))
+	if !ok {
+		c.errorf(sbc.Pos, "undefined subroutine '%s' in class %s", string(sbc.Sn), cn)
+	}
+	return sub, ok
+}
+
+// checkArgs reports sbc if the number of its arguments differs from the parameters of sub.
+func (c *callChecker) checkArgs(sbc *element.SubroutineCall, sub *Subroutine) {
+	if len(sbc.ExpL) != len(sub.Params) {
+		c.errorf(sbc.Pos, "'%s.%s' takes %d arguments, got %d", sub.Class, sub.Name, len(sub.Params), len(sbc.ExpL))
+	}
+}
+
+func (c *callChecker) errorf(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, check.Diagnostic{
+		Pos:      pos,
+		Severity: check.ERROR,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+func isPrimitive(t string) bool {
+	return t == "int" || t == "char" || t == "boolean"
+}
@@ -0,0 +1,64 @@
+package program
+
+import (
+	"jackanalyzer/element"
+)
+
+// Subroutine is the signature of a subroutine.
+type Subroutine struct {
+	Class  string
+	Name   string
+	Kind   string   // 'constructor' | 'function' | 'method'
+	Params []string // the types of the parameters
+	Return string   // 'void' | type
+}
+
+// Index is the global class/subroutine index.
+// Index[className][subroutineName] is the signature.
+type Index map[string]map[string]*Subroutine
+
+// NewIndex returns Index of the Jack OS classes.
+func NewIndex() Index {
+	idx := Index{}
+	for _, cl := range OSClasses() {
+		idx.Add(cl)
+	}
+	return idx
+}
+
+// Add adds the subroutines of cl.
+// The class of the same name, e.g. a user implementation of the Jack OS, is replaced.
+func (idx Index) Add(cl *element.Class) {
+	cn := string(cl.Cn)
+	idx[cn] = map[string]*Subroutine{}
+	for _, sd := range cl.Sds {
+		sub := &Subroutine{
+			Class:  cn,
+			Name:   string(sd.Sn),
+			Kind:   string(sd.Modi),
+			Return: element.TypeName(sd.St),
+		}
+		if sd.Pl != nil {
+			sub.Params = append(sub.Params, element.TypeName(sd.Pl.Type))
+			for _, v := range sd.Pl.Next {
+				sub.Params = append(sub.Params, element.TypeName(v.Type))
+			}
+		}
+		// the first declaration wins as check.Check reports the others
+		if _, ok := idx[cn][sub.Name]; !ok {
+			idx[cn][sub.Name] = sub
+		}
+	}
+}
+
+// HasClass reports whether the class of name is indexed.
+func (idx Index) HasClass(name string) bool {
+	_, ok := idx[name]
+	return ok
+}
+
+// Lookup returns the subroutine className.subroutineName.
+func (idx Index) Lookup(className, subroutineName string) (*Subroutine, bool) {
+	sub, ok := idx[className][subroutineName]
+	return sub, ok
+}
@@ -0,0 +1,158 @@
+package program
+
+import (
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"strings"
+)
+
+// OSClassNames is the names of the Jack OS classes in the order of OSClasses.
+var OSClassNames = []string{"Math", "String", "Array", "Output", "Screen", "Keyboard", "Memory", "Sys"}
+
+// osSources is the declaration stubs of the Jack OS classes.
+// The subroutine bodies are empty; only the signatures are analyzed.
+var osSources = map[string]string{
+	"Math": `/** A library of commonly used mathematical functions. */
+class Math {
+  /** Initializes the library. */
+  function void init() {}
+  /** Returns the absolute value of x. */
+  function int abs(int x) {}
+  /** Returns the product of x and y. */
+  function int multiply(int x, int y) {}
+  /** Returns the integer part of x / y. */
+  function int divide(int x, int y) {}
+  /** Returns the minimum of x and y. */
+  function int min(int x, int y) {}
+  /** Returns the maximum of x and y. */
+  function int max(int x, int y) {}
+  /** Returns the integer part of the square root of x. */
+  function int sqrt(int x) {}
+}`,
+	"String": `/** Represents character strings. */
+class String {
+  /** Constructs a new empty string with a maximum length of maxLength. */
+  constructor String new(int maxLength) {}
+  /** Disposes this string. */
+  method void dispose() {}
+  /** Returns the current length of this string. */
+  method int length() {}
+  /** Returns the character at the j-th location of this string. */
+  method char charAt(int j) {}
+  /** Sets the character at the j-th location of this string to c. */
+  method void setCharAt(int j, char c) {}
+  /** Appends c to the end of this string and returns this string. */
+  method String appendChar(char c) {}
+  /** Erases the last character from this string. */
+  method void eraseLastChar() {}
+  /** Returns the integer value of this string until a non-digit character is detected. */
+  method int intValue() {}
+  /** Sets this string to hold a representation of the given value. */
+  method void setInt(int val) {}
+  /** Returns the backspace character. */
+  function char backSpace() {}
+  /** Returns the double quote character. */
+  function char doubleQuote() {}
+  /** Returns the newline character. */
+  function char newLine() {}
+}`,
+	"Array": `/** Represents an array. */
+class Array {
+  /** Constructs a new array of the given size. */
+  function Array new(int size) {}
+  /** Disposes this array. */
+  method void dispose() {}
+}`,
+	"Output": `/** A library of functions for writing text on the screen. */
+class Output {
+  /** Initializes the screen and locates the cursor at the screen's top-left. */
+  function void init() {}
+  /** Moves the cursor to the j-th column of the i-th row and erases the character displayed there. */
+  function void moveCursor(int i, int j) {}
+  /** Displays c at the cursor location and advances the cursor one column forward. */
+  function void printChar(char c) {}
+  /** Displays s starting at the cursor location and advances the cursor appropriately. */
+  function void printString(String s) {}
+  /** Displays i starting at the cursor location and advances the cursor appropriately. */
+  function void printInt(int i) {}
+  /** Advances the cursor to the beginning of the next line. */
+  function void println() {}
+  /** Moves the cursor one column back. */
+  function void backSpace() {}
+}`,
+	"Screen": `/** A library of functions for displaying graphics on the screen. */
+class Screen {
+  /** Initializes the screen. */
+  function void init() {}
+  /** Erases the entire screen. */
+  function void clearScreen() {}
+  /** Sets the current color to be used by the draw functions: white (false) or black (true). */
+  function void setColor(boolean b) {}
+  /** Draws the (x,y) pixel using the current color. */
+  function void drawPixel(int x, int y) {}
+  /** Draws a line from pixel (x1,y1) to pixel (x2,y2) using the current color. */
+  function void drawLine(int x1, int y1, int x2, int y2) {}
+  /** Draws a filled rectangle whose top left corner is (x1,y1) and bottom right corner is (x2,y2). */
+  function void drawRectangle(int x1, int y1, int x2, int y2) {}
+  /** Draws a filled circle of radius r around (x,y) using the current color. */
+  function void drawCircle(int x, int y, int r) {}
+}`,
+	"Keyboard": `/** A library for handling user input from the keyboard. */
+class Keyboard {
+  /** Initializes the keyboard. */
+  function void init() {}
+  /** Returns the character of the currently pressed key, or 0 if no key is pressed. */
+  function char keyPressed() {}
+  /** Waits until a key is pressed and released, echoes it to the screen and returns it. */
+  function char readChar() {}
+  /** Displays message, reads a line until newline, echoes it to the screen and returns it. */
+  function String readLine(String message) {}
+  /** Displays message, reads a line until newline, echoes it to the screen and returns its integer value. */
+  function int readInt(String message) {}
+}`,
+	"Memory": `/** A library of functions for direct access to the RAM and for managing the heap. */
+class Memory {
+  /** Initializes the library. */
+  function void init() {}
+  /** Returns the RAM value at the given address. */
+  function int peek(int address) {}
+  /** Sets the RAM value at the given address to the given value. */
+  function void poke(int address, int value) {}
+  /** Finds an available RAM block of the given size and returns a reference to its base address. */
+  function Array alloc(int size) {}
+  /** De-allocates the given object and makes it available for future allocations. */
+  function void deAlloc(Array o) {}
+}`,
+	"Sys": `/** A library of basic system services. */
+class Sys {
+  /** Performs all the initializations required by the OS, then calls Main.main. */
+  function void init() {}
+  /** Halts the program execution. */
+  function void halt() {}
+  /** Displays the given error code in the form "ERR<errorCode>" and halts. */
+  function void error(int errorCode) {}
+  /** Waits approximately duration milliseconds and returns. */
+  function void wait(int duration) {}
+}`,
+}
+
+// OSClasses returns the declaration stubs of the Jack OS classes with their doc comments.
+// Each call parses the stubs again, so the caller may modify them.
+func OSClasses() []*element.Class {
+	cls := make([]*element.Class, len(OSClassNames))
+	for i, name := range OSClassNames {
+		cl, err := cmplengn.ParseScanner(tokenizer.NewScanner(strings.NewReader(osSources[name]), tokenizer.WithTrivia()))
+		if err != nil {
+			panic("program: invalid OS stub " + name + ": " + err.Error())
+		}
+		cls[i] = cl
+	}
+	return cls
+}
+
+// IsOSClass reports whether name is a Jack OS class.
+func IsOSClass(name string) bool {
+	_, ok := osSources[name]
+	return ok
+}
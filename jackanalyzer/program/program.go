@@ -0,0 +1,89 @@
+package program
+
+import (
+	"io/ioutil"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"os"
+	"path/filepath"
+)
+
+// File is a .jack source of Program.
+type File struct {
+	Path  string
+	Class *element.Class // partial class on the syntax errors, nil on the tokenize error
+	Err   error          // *tokenizer.Error or cmplengn.ErrorList
+}
+
+// Program is every .jack source in a directory.
+type Program struct {
+	Files []*File
+	OS    []*element.Class // the Jack OS stubs not implemented by Files
+	Index Index            // the classes of Files and OS
+}
+
+// Load parses every .jack file directly under dir with the doc comments.
+// A .jack file implementing a Jack OS class, e.g. Math.jack, overrides its stub.
+// The error of each file is in File.Err; Load returns an error only if dir cannot be read.
+func Load(dir string) (*Program, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{Index: Index{}}
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".jack" {
+			continue
+		}
+		f := parseFile(filepath.Join(dir, fi.Name()))
+		if f.Class != nil {
+			p.Index.Add(f.Class)
+		}
+		p.Files = append(p.Files, f)
+	}
+	for _, cl := range OSClasses() {
+		if !p.Index.HasClass(string(cl.Cn)) {
+			p.OS = append(p.OS, cl)
+			p.Index.Add(cl)
+		}
+	}
+	return p, nil
+}
+
+// Class returns the class of name, either of Files or the Jack OS stub.
+func (p *Program) Class(name string) (*element.Class, bool) {
+	for _, f := range p.Files {
+		if f.Class != nil && string(f.Class.Cn) == name {
+			return f.Class, true
+		}
+	}
+	for _, cl := range p.OS {
+		if string(cl.Cn) == name {
+			return cl, true
+		}
+	}
+	return nil, false
+}
+
+// File returns the file of path.
+func (p *Program) File(path string) (*File, bool) {
+	for _, f := range p.Files {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func parseFile(path string) *File {
+	f := &File{Path: path}
+	src, err := os.Open(path)
+	if err != nil {
+		f.Err = err
+		return f
+	}
+	defer src.Close()
+	f.Class, f.Err = cmplengn.ParseScanner(tokenizer.NewScanner(src, tokenizer.WithTrivia()))
+	return f
+}
@@ -0,0 +1,273 @@
+package program
+
+import (
+	"fmt"
+	"io/ioutil"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/tokenizer"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, s string) *element.Class {
+	t.Helper()
+	cl, err := cmplengn.ParseScanner(tokenizer.NewScanner(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Main.jack":  "class Main { function void main() { var Point p; let p = Point.new(1, 2); do p.print(); return; } }",
+		"Point.jack": "class Point { field int x, y; constructor Point new(int ax, int ay) { return this; } method void print() { return; } }",
+		"Bad.jack":   "class Bad { function void f( }",
+		"README.md":  "not jack",
+	}
+	for name, s := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Files) != 3 {
+		t.Fatalf("len(Files) = %v, want 3", len(p.Files))
+	}
+	bad, ok := p.File(filepath.Join(dir, "Bad.jack"))
+	if !ok || bad.Err == nil {
+		t.Errorf("File(Bad.jack) = %v, %v, want syntax error", bad, ok)
+	}
+	sub, ok := p.Index.Lookup("Point", "new")
+	if !ok {
+		t.Fatal("Lookup(Point, new) not found")
+	}
+	if sub.Kind != "constructor" || strings.Join(sub.Params, ",") != "int,int" || sub.Return != "Point" {
+		t.Errorf("Lookup(Point, new) = %+v", sub)
+	}
+	if _, ok := p.Index.Lookup("Output", "printInt"); !ok {
+		t.Error("Lookup(Output, printInt) not found")
+	}
+	main, _ := p.File(filepath.Join(dir, "Main.jack"))
+	if diags := CheckCalls(main.Class, p.Index); len(diags) != 0 {
+		t.Errorf("CheckCalls(Main) = %v", diags)
+	}
+}
+
+func TestLoad_osOverride(t *testing.T) {
+	dir := t.TempDir()
+	src := "class Math { function int abs(int x, int y) { return x; } }"
+	if err := ioutil.WriteFile(filepath.Join(dir, "Math.jack"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.OS) != len(OSClassNames)-1 {
+		t.Errorf("len(OS) = %v, want %v", len(p.OS), len(OSClassNames)-1)
+	}
+	cl, ok := p.Class("Math")
+	if !ok || cl != p.Files[0].Class {
+		t.Errorf("Class(Math) = %v, %v, want Math.jack", cl, ok)
+	}
+	if _, ok := p.Class("Sys"); !ok {
+		t.Error("Class(Sys) not found")
+	}
+	if _, ok := p.Index.Lookup("Math", "sqrt"); ok {
+		t.Error("Lookup(Math, sqrt) found, want the stub overridden")
+	}
+}
+
+func TestOSClasses(t *testing.T) {
+	cls := OSClasses()
+	if len(cls) != len(OSClassNames) {
+		t.Fatalf("len(OSClasses()) = %v, want %v", len(cls), len(OSClassNames))
+	}
+	for i, cl := range cls {
+		if string(cl.Cn) != OSClassNames[i] || !IsOSClass(OSClassNames[i]) {
+			t.Errorf("OSClasses()[%d] = %v, want %v", i, string(cl.Cn), OSClassNames[i])
+		}
+		if len(cl.Sds) == 0 {
+			t.Errorf("%v has no subroutines", OSClassNames[i])
+		}
+	}
+	if IsOSClass("Main") {
+		t.Error("IsOSClass(Main) = true")
+	}
+	if OSClasses()[0] == cls[0] {
+		t.Error("OSClasses() returns the shared classes")
+	}
+}
+
+func TestIndex_Add(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(parse(t, "class Math { function int abs(int x, int y) { return x; } }"))
+	sub, ok := idx.Lookup("Math", "abs")
+	if !ok || len(sub.Params) != 2 {
+		t.Errorf("Lookup(Math, abs) = %+v, %v, want the user class", sub, ok)
+	}
+	if _, ok := idx.Lookup("Math", "sqrt"); ok {
+		t.Error("Lookup(Math, sqrt) found, want the OS class replaced")
+	}
+}
+
+func TestCheckCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			"resolved",
+			`class Main {
+  field Array a;
+  field String s;
+  constructor Main new() { do init(); return this; }
+  method void init() { let a = Array.new(3); let s = String.new(1); do s.appendChar(65); return; }
+  function void main() {
+    var Main m;
+    var int x;
+    let m = Main.new();
+    do m.init();
+    let x = Math.max(Math.abs(-1), Keyboard.readInt("n"));
+    do Output.printString(m.str());
+    do Sys.halt();
+    return;
+  }
+  method String str() { return s; }
+}`,
+			nil,
+		},
+		{
+			"unresolved",
+			`class Main {
+  field Foo f;
+  function void main() {
+    var String s;
+    do init();
+    do Main.init();
+    do Main.foo();
+    do Bar.baz();
+    do f.baz();
+    do s.newLine();
+    do Output.printInt(1, 2);
+    do Math.sqrt();
+    do Main.main(1);
+    return;
+  }
+  method void init() { do main(); do run(1); return; }
+}`,
+			[]string{
+				"5:8: method 'Main.init' called from function 'main'",
+				"6:8: method 'Main.init' called as a function",
+				"7:8: undefined subroutine 'foo' in class Main",
+				"8:8: undefined class 'Bar'",
+				"9:8: undefined class 'Foo'",
+				"10:8: function 'String.newLine' called as a method on 's'",
+				"11:8: 'Output.printInt' takes 1 arguments, got 2",
+				"12:8: 'Math.sqrt' takes 1 arguments, got 0",
+				"13:8: 'Main.main' takes 0 arguments, got 1",
+				"16:27: function 'Main.main' called as a method",
+				"16:38: undefined subroutine 'run' in class Main",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := parse(t, tt.s)
+			idx := NewIndex()
+			idx.Add(cl)
+			got := CheckCalls(cl, idx)
+			var gots []string
+			for _, d := range got {
+				gots = append(gots, d.String())
+			}
+			if strings.Join(gots, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("CheckCalls() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestProgram_Unused(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Main.jack": `class Main {
+  static int count, total;
+  static Array buf;
+  function void main() {
+    var Game g;
+    let g = Game.new(1, 2);
+    do g.run();
+    let count = 0;
+    let buf[0] = 1;
+    return;
+  }
+}`,
+		"Game.jack": `class Game {
+  field int w, h;
+  field Array cells;
+  constructor Game new(int aw, int ah) {
+    let w = aw;
+    return this;
+  }
+  method void run() {
+    do draw(w);
+    return;
+  }
+  method void draw(int x) {
+    let x = 0;
+    do Output.printInt(h);
+    return;
+  }
+  function void unused(int y) {
+    do Game.unused(y);
+    return;
+  }
+}`,
+	}
+	for name, s := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gots []string
+	for _, u := range p.Unused() {
+		gots = append(gots, fmt.Sprintf("%s:%d:%d: %s %s.%s %s", filepath.Base(u.File), u.Line, u.Column, u.Kind, u.Class, u.Subroutine, u.Name))
+	}
+	want := []string{
+		"Game.jack:3:15: field Game. cells",
+		"Game.jack:4:36: parameter Game.new ah",
+		"Game.jack:12:24: parameter Game.draw x",
+		"Game.jack:17:17: subroutine Game. unused",
+		"Main.jack:2:14: static Main. count",
+		"Main.jack:2:21: static Main. total",
+	}
+	if strings.Join(gots, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unused() = \n%v\nwant \n%v", strings.Join(gots, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Game.unused may be called in the broken code
+	broken := "class Broken {\n  function void f() {\n    do Game.unused(1)\n    return;\n  }\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "Broken.jack"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Unused(); got != nil {
+		t.Errorf("Unused() with a syntax error = %v, want nil", got)
+	}
+}
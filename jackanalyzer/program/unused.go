@@ -0,0 +1,235 @@
+package program
+
+import (
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"sort"
+)
+
+// Unused is a declaration which is never referenced.
+type Unused struct {
+	Kind       string `json:"kind"` // 'field' | 'static' | 'parameter' | 'subroutine'
+	Class      string `json:"class"`
+	Subroutine string `json:"subroutine,omitempty"` // the subroutine of the parameter
+	Name       string `json:"name"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+
+	pos token.Pos
+}
+
+// decl is a declared variable.
+type decl struct {
+	name string
+	pos  token.Pos
+}
+
+// The entry points of the call graph.
+// Sys.init is the entry of the VM and calls Main.main.
+var entries = []string{"Main.main", "Sys.init"}
+
+// usage collects the references and the calls of a class.
+type usage struct {
+	idx   Index
+	cl    *element.Class
+	st    *symboltable.SymbolTable
+	used  map[*symboltable.Symbol]bool
+	calls []string // 'className.subroutineName' called by the current subroutine
+}
+
+// Unused returns the fields and statics never read, the parameters never read
+// and the subroutines never called from Main.main transitively, in the order of Files.
+// Assigning a variable by let is not a read, but assigning an element of an array is.
+// The subroutines are not reported if Main.main is not in Files.
+// It returns nil if a file has errors, since the references in its broken code are unknown.
+func (p *Program) Unused() []Unused {
+	for _, f := range p.Files {
+		if f.Err != nil {
+			return nil
+		}
+	}
+	var unused []Unused
+	graph := map[string][]string{}
+	decls := map[string]Unused{}
+	var order []string
+	for _, f := range p.Files {
+		unused = append(unused, p.unusedVars(f, graph)...)
+		for _, sd := range f.Class.Sds {
+			name := string(f.Class.Cn) + "." + string(sd.Sn)
+			decls[name] = newUnused("subroutine", f, sd.SnPos, string(f.Class.Cn), "", string(sd.Sn))
+			order = append(order, name)
+		}
+	}
+
+	if _, ok := decls[entries[0]]; ok {
+		reached := map[string]bool{}
+		var visit func(string)
+		visit = func(name string) {
+			if reached[name] {
+				return
+			}
+			reached[name] = true
+			for _, callee := range graph[name] {
+				visit(callee)
+			}
+		}
+		for _, e := range entries {
+			visit(e)
+		}
+		for _, name := range order {
+			if !reached[name] {
+				unused = append(unused, decls[name])
+			}
+		}
+	}
+
+	files := map[string]int{}
+	for i, f := range p.Files {
+		files[f.Path] = i
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		if unused[i].File != unused[j].File {
+			return files[unused[i].File] < files[unused[j].File]
+		}
+		return unused[i].pos.Offset < unused[j].pos.Offset
+	})
+	return unused
+}
+
+// unusedVars returns the unused fields, statics and parameters of f
+// and adds the calls of its subroutines to graph.
+func (p *Program) unusedVars(f *File, graph map[string][]string) []Unused {
+	cl := f.Class
+	cn := string(cl.Cn)
+	u := &usage{
+		idx:  p.Index,
+		cl:   cl,
+		st:   symboltable.NewClass(cl),
+		used: map[*symboltable.Symbol]bool{},
+	}
+
+	var unused []Unused
+	for _, sd := range cl.Sds {
+		u.st.StartSubroutineDec(sd)
+		u.calls = nil
+		u.statements(sd.Sb.Stmts)
+		graph[cn+"."+string(sd.Sn)] = u.calls
+
+		if sd.Pl == nil {
+			continue
+		}
+		params := []decl{{string(sd.Pl.Vn), sd.Pl.VnPos}}
+		for _, v := range sd.Pl.Next {
+			params = append(params, decl{string(v.Vn), v.VnPos})
+		}
+		for _, param := range params {
+			if s, ok := u.st.Lookup(param.name); ok && s.Kind == symboltable.ARG && !u.used[s] {
+				unused = append(unused, newUnused("parameter", f, param.pos, cn, string(sd.Sn), param.name))
+			}
+		}
+	}
+
+	for _, cvd := range cl.Cvds {
+		vars := []decl{{string(cvd.Vn), cvd.VnPos}}
+		for _, v := range cvd.Vns {
+			vars = append(vars, decl{string(v.Vn), v.VnPos})
+		}
+		for _, v := range vars {
+			s, ok := u.st.Lookup(v.name)
+			if !ok || u.used[s] {
+				continue
+			}
+			unused = append(unused, newUnused(s.Kind.String(), f, v.pos, cn, "", v.name))
+		}
+	}
+	return unused
+}
+
+func newUnused(kind string, f *File, pos token.Pos, class, subroutine, name string) Unused {
+	return Unused{
+		Kind:       kind,
+		Class:      class,
+		Subroutine: subroutine,
+		Name:       name,
+		File:       f.Path,
+		Line:       pos.Line,
+		Column:     pos.Column,
+		pos:        pos,
+	}
+}
+
+func (u *usage) statements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			if s.Lexp != nil {
+				// the array is read to assign its element
+				u.use(string(s.Vn))
+				u.expression(s.Lexp)
+			}
+			u.expression(&s.Rexp)
+		case *element.IfStatement:
+			u.expression(&s.LExp)
+			u.statements(s.Stmts)
+			u.statements(s.EStmts)
+		case *element.WhileStatement:
+			u.expression(&s.Exp)
+			u.statements(s.Stmts)
+		case *element.DoStatement:
+			u.subroutineCall(s.Sub)
+		case *element.ReturnStatement:
+			if s.Exp != nil {
+				u.expression(s.Exp)
+			}
+		}
+	}
+}
+
+func (u *usage) expression(exp *element.Expression) {
+	u.term(exp.Term)
+	for _, v := range exp.Next {
+		u.term(v.Term)
+	}
+}
+
+func (u *usage) term(term element.Term) {
+	switch t := term.(type) {
+	case *element.VarName:
+		u.use(string(t.V))
+	case *element.CallIndex:
+		u.use(string(t.Vn))
+		u.expression(&t.Exp)
+	case *element.SubroutineCall:
+		u.subroutineCall(t)
+	case *element.Args:
+		u.expression(&t.Exp)
+	case *element.UopTerm:
+		u.term(t.Term)
+	}
+}
+
+// subroutineCall records the callee of sbc resolved like CheckCalls.
+func (u *usage) subroutineCall(sbc *element.SubroutineCall) {
+	for i := range sbc.ExpL {
+		u.expression(&sbc.ExpL[i])
+	}
+	cn := string(u.cl.Cn)
+	if sbc.Dot != "" {
+		cn = string(sbc.Name)
+		if s, ok := u.st.Lookup(cn); ok {
+			u.used[s] = true
+			cn = s.Type
+		}
+	}
+	if _, ok := u.idx.Lookup(cn, string(sbc.Sn)); ok {
+		u.calls = append(u.calls, cn+"."+string(sbc.Sn))
+	}
+}
+
+func (u *usage) use(name string) {
+	if s, ok := u.st.Lookup(name); ok {
+		u.used[s] = true
+	}
+}
@@ -0,0 +1,180 @@
+package symboltable
+
+import (
+	"jackanalyzer/element"
+)
+
+// Kind is the kind of the identifier.
+type Kind int
+
+const (
+	NONE Kind = iota
+	STATIC
+	FIELD
+	ARG
+	VAR
+)
+
+var kinds = map[Kind]string{
+	NONE:   "none",
+	STATIC: "static",
+	FIELD:  "field",
+	ARG:    "argument",
+	VAR:    "var",
+}
+
+func (k Kind) String() string {
+	return kinds[k]
+}
+
+// Symbol is an entry of SymbolTable.
+type Symbol struct {
+	Name  string
+	Type  string // 'int' | 'char' | 'boolean' | className
+	Kind  Kind
+	Index int // running index in the kind
+}
+
+// SymbolTable has the class scope ('static', 'field')
+// and the subroutine scope ('argument', 'var').
+type SymbolTable struct {
+	className  string
+	class      map[string]*Symbol
+	subroutine map[string]*Symbol
+	counts     map[Kind]int
+}
+
+// New returns SymbolTable with the empty scopes.
+func New() *SymbolTable {
+	st := &SymbolTable{
+		class:      map[string]*Symbol{},
+		subroutine: map[string]*Symbol{},
+		counts:     map[Kind]int{},
+	}
+	return st
+}
+
+// NewClass returns SymbolTable whose class scope is defined by cl.Cvds.
+func NewClass(cl *element.Class) *SymbolTable {
+	st := New()
+	st.className = string(cl.Cn)
+	for _, cvd := range cl.Cvds {
+		st.DefineClassVarDec(cvd)
+	}
+	return st
+}
+
+// StartSubroutine resets the subroutine scope.
+func (st *SymbolTable) StartSubroutine() {
+	st.subroutine = map[string]*Symbol{}
+	st.counts[ARG] = 0
+	st.counts[VAR] = 0
+}
+
+// StartSubroutineDec resets the subroutine scope and defines the parameters and the locals of sd.
+//
+// For the method, 'this' is defined as argument 0 whose type is the class of NewClass.
+func (st *SymbolTable) StartSubroutineDec(sd *element.SubroutineDec) {
+	st.StartSubroutine()
+	if sd.Modi == "method" {
+		st.Define("this", st.className, ARG)
+	}
+	st.DefineParameterList(sd.Pl)
+	for _, vd := range sd.Sb.Vd {
+		st.DefineVarDec(vd)
+	}
+}
+
+// Define defines the new identifier and returns it.
+// STATIC and FIELD are in the class scope, ARG and VAR are in the subroutine scope.
+//
+// If the name is already defined in the same scope, it is redefined with the next index.
+func (st *SymbolTable) Define(name, typ string, kind Kind) *Symbol {
+	s := &Symbol{
+		Name:  name,
+		Type:  typ,
+		Kind:  kind,
+		Index: st.counts[kind],
+	}
+	st.counts[kind]++
+	switch kind {
+	case STATIC, FIELD:
+		st.class[name] = s
+	case ARG, VAR:
+		st.subroutine[name] = s
+	}
+	return s
+}
+
+// DefineClassVarDec defines the names of classVarDec.
+func (st *SymbolTable) DefineClassVarDec(cvd *element.ClassVarDec) {
+	kind := FIELD
+	if cvd.Modi == "static" {
+		kind = STATIC
+	}
+	typ := element.TypeName(cvd.Vt)
+	st.Define(string(cvd.Vn), typ, kind)
+	for _, v := range cvd.Vns {
+		st.Define(string(v.Vn), typ, kind)
+	}
+}
+
+// DefineParameterList defines the parameters as ARG.
+// pl is nil for the empty parameterList.
+func (st *SymbolTable) DefineParameterList(pl *element.ParameterList) {
+	if pl == nil {
+		return
+	}
+	st.Define(string(pl.Vn), element.TypeName(pl.Type), ARG)
+	for _, v := range pl.Next {
+		st.Define(string(v.Vn), element.TypeName(v.Type), ARG)
+	}
+}
+
+// DefineVarDec defines the names of varDec as VAR.
+func (st *SymbolTable) DefineVarDec(vd *element.VarDec) {
+	typ := element.TypeName(vd.Vt)
+	st.Define(string(vd.Vn), typ, VAR)
+	for _, v := range vd.Vns {
+		st.Define(string(v.Vn), typ, VAR)
+	}
+}
+
+// Lookup returns the symbol of name.
+// The subroutine scope shadows the class scope.
+func (st *SymbolTable) Lookup(name string) (*Symbol, bool) {
+	if s, ok := st.subroutine[name]; ok {
+		return s, true
+	}
+	s, ok := st.class[name]
+	return s, ok
+}
+
+// VarCount returns the number of the identifiers of kind defined in the current scope.
+func (st *SymbolTable) VarCount(kind Kind) int {
+	return st.counts[kind]
+}
+
+// KindOf returns the kind of name. It returns NONE if name is not defined.
+func (st *SymbolTable) KindOf(name string) Kind {
+	if s, ok := st.Lookup(name); ok {
+		return s.Kind
+	}
+	return NONE
+}
+
+// TypeOf returns the type of name. It returns "" if name is not defined.
+func (st *SymbolTable) TypeOf(name string) string {
+	if s, ok := st.Lookup(name); ok {
+		return s.Type
+	}
+	return ""
+}
+
+// IndexOf returns the index of name. It returns -1 if name is not defined.
+func (st *SymbolTable) IndexOf(name string) int {
+	if s, ok := st.Lookup(name); ok {
+		return s.Index
+	}
+	return -1
+}
@@ -0,0 +1,144 @@
+package symboltable
+
+import (
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/tokenizer"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSymbolTable_Define(t *testing.T) {
+	st := New()
+	st.Define("x", "int", FIELD)
+	st.Define("y", "int", FIELD)
+	st.Define("count", "int", STATIC)
+	st.Define("a", "Array", ARG)
+	st.Define("x", "char", VAR)
+
+	tests := []struct {
+		name      string
+		wantKind  Kind
+		wantType  string
+		wantIndex int
+	}{
+		{"y", FIELD, "int", 1},
+		{"count", STATIC, "int", 0},
+		{"a", ARG, "Array", 0},
+		{"x", VAR, "char", 0}, // subroutine scope shadows class scope
+		{"undefined", NONE, "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := st.KindOf(tt.name); got != tt.wantKind {
+				t.Errorf("KindOf() = %v, want %v", got, tt.wantKind)
+			}
+			if got := st.TypeOf(tt.name); got != tt.wantType {
+				t.Errorf("TypeOf() = %v, want %v", got, tt.wantType)
+			}
+			if got := st.IndexOf(tt.name); got != tt.wantIndex {
+				t.Errorf("IndexOf() = %v, want %v", got, tt.wantIndex)
+			}
+		})
+	}
+
+	st.StartSubroutine()
+	if got := st.KindOf("x"); got != FIELD {
+		t.Errorf("KindOf() after StartSubroutine = %v, want %v", got, FIELD)
+	}
+	if got := st.KindOf("a"); got != NONE {
+		t.Errorf("KindOf() after StartSubroutine = %v, want %v", got, NONE)
+	}
+	if got := st.VarCount(ARG); got != 0 {
+		t.Errorf("VarCount() after StartSubroutine = %v, want 0", got)
+	}
+	if got := st.VarCount(FIELD); got != 2 {
+		t.Errorf("VarCount() after StartSubroutine = %v, want 2", got)
+	}
+}
+
+func TestSymbolTable_StartSubroutineDec(t *testing.T) {
+	s := `
+class Point {
+	field int x, y;
+	static int count;
+	constructor Point new(int Ax, int Ay) {
+		return this;
+	}
+	method int distance(Point other) {
+		var int dx, dy;
+		var boolean b;
+		return dx;
+	}
+}
+`
+	head, err := tokenizer.New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := cmplengn.Parse(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := NewClass(cl)
+	tests := []struct {
+		name  string
+		sd    int
+		names []string
+		want  []Symbol
+	}{
+		{
+			"constructor",
+			0,
+			[]string{"x", "y", "count", "Ax", "Ay"},
+			[]Symbol{
+				{Name: "x", Type: "int", Kind: FIELD, Index: 0},
+				{Name: "y", Type: "int", Kind: FIELD, Index: 1},
+				{Name: "count", Type: "int", Kind: STATIC, Index: 0},
+				{Name: "Ax", Type: "int", Kind: ARG, Index: 0},
+				{Name: "Ay", Type: "int", Kind: ARG, Index: 1},
+			},
+		},
+		{
+			"method",
+			1,
+			[]string{"this", "other", "dx", "dy", "b"},
+			[]Symbol{
+				{Name: "this", Type: "Point", Kind: ARG, Index: 0},
+				{Name: "other", Type: "Point", Kind: ARG, Index: 1},
+				{Name: "dx", Type: "int", Kind: VAR, Index: 0},
+				{Name: "dy", Type: "int", Kind: VAR, Index: 1},
+				{Name: "b", Type: "boolean", Kind: VAR, Index: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st.StartSubroutineDec(cl.Sds[tt.sd])
+			var got []Symbol
+			for _, n := range tt.names {
+				s, ok := st.Lookup(n)
+				if !ok {
+					t.Fatalf("Lookup(%s) is not found", n)
+				}
+				got = append(got, *s)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := st.VarCount(VAR); got != 3 {
+		t.Errorf("VarCount(VAR) = %v, want 3", got)
+	}
+	if got := st.KindOf("Ax"); got != NONE {
+		t.Errorf("KindOf(Ax) = %v, want %v", got, NONE)
+	}
+}
+
+func TestKind_String(t *testing.T) {
+	if got := ARG.String(); got != "argument" {
+		t.Errorf("String() = %v, want argument", got)
+	}
+}
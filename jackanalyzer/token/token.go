@@ -1,9 +1,27 @@
 package token
 
+import (
+	"strconv"
+	"strings"
+)
+
 type TokenType int
 type Keyword string
 
+// Pos is a position in the source.
+type Pos struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in runes, starting at 1
+}
+
+// String returns "line:column".
+func (p Pos) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
 type Token struct {
+	Pos        // position of the first character
 	Next       *Token
 	TokenType  TokenType
 	Keyword    Keyword
@@ -11,6 +29,35 @@ type Token struct {
 	Identifier string
 	IntVal     int
 	StringVal  string
+
+	// The fields below are set only by the tokenizer with the trivia option.
+	// Concatenating Leading, Lit and Trailing of all the tokens
+	// and the trivia after the last token reproduces the source.
+	Lit      string   // the source text of the token
+	Leading  []Trivia // the trivia after the previous token's Trailing
+	Trailing []Trivia // the trivia on the same line after the token, up to the newline
+}
+
+// TriviaKind is a kind of Trivia.
+type TriviaKind int
+
+const (
+	_ TriviaKind = iota
+	WHITESPACE
+	LINE_COMMENT  // '//' comment, without the newline
+	BLOCK_COMMENT // '/*' '*/' comment, possibly spanning lines
+)
+
+// Trivia is a run of the source text between the tokens.
+type Trivia struct {
+	Pos  // position of the first character
+	Kind TriviaKind
+	Text string // the source text as is, including '//' or '/*' '*/'
+}
+
+// IsDoc reports whether t is a '/** */' doc comment.
+func (t Trivia) IsDoc() bool {
+	return t.Kind == BLOCK_COMMENT && strings.HasPrefix(t.Text, "/**") && t.Text != "/**/"
 }
 
 const (
@@ -135,13 +182,7 @@ func (t *Token) HasMoreTokens() bool {
 	return t.Next != nil
 }
 
+// Advance replaces t with the next token, including its trivia.
 func (t *Token) Advance() {
-	nxt := t.Next
-	t.Next = nxt.Next
-	t.TokenType = nxt.TokenType
-	t.Keyword = nxt.Keyword
-	t.Symbol = nxt.Symbol
-	t.Identifier = nxt.Identifier
-	t.IntVal = nxt.IntVal
-	t.StringVal = nxt.StringVal
+	*t = *t.Next
 }
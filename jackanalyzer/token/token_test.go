@@ -180,6 +180,7 @@ func TestToken_Advance(t *testing.T) {
 			"test",
 			&Token{
 				Next: &Token{
+					Pos:       Pos{Offset: 5, Line: 1, Column: 6},
 					TokenType: INT_CONST,
 					IntVal:    1234,
 				},
@@ -187,10 +188,35 @@ func TestToken_Advance(t *testing.T) {
 				Identifier: "hoge",
 			},
 			&Token{
+				Pos:       Pos{Offset: 5, Line: 1, Column: 6},
 				TokenType: INT_CONST,
 				IntVal:    1234,
 			},
 		},
+		{
+			"trivia",
+			&Token{
+				Next: &Token{
+					Pos:        Pos{Offset: 6, Line: 1, Column: 7},
+					TokenType:  IDENTIFIER,
+					Identifier: "x",
+					Lit:        "x",
+					Leading:    []Trivia{{Pos: Pos{Offset: 5, Line: 1, Column: 6}, Kind: WHITESPACE, Text: " "}},
+					Trailing:   []Trivia{{Pos: Pos{Offset: 7, Line: 1, Column: 8}, Kind: LINE_COMMENT, Text: "// c"}},
+				},
+				TokenType: KEYWORD,
+				Keyword:   LET,
+				Lit:       "let",
+			},
+			&Token{
+				Pos:        Pos{Offset: 6, Line: 1, Column: 7},
+				TokenType:  IDENTIFIER,
+				Identifier: "x",
+				Lit:        "x",
+				Leading:    []Trivia{{Pos: Pos{Offset: 5, Line: 1, Column: 6}, Kind: WHITESPACE, Text: " "}},
+				Trailing:   []Trivia{{Pos: Pos{Offset: 7, Line: 1, Column: 8}, Kind: LINE_COMMENT, Text: "// c"}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -305,3 +331,10 @@ func TestToken_IsOp(t *testing.T) {
 		})
 	}
 }
+
+func TestPos_String(t *testing.T) {
+	p := Pos{Offset: 30, Line: 12, Column: 7}
+	if got := p.String(); got != "12:7" {
+		t.Errorf("Pos.String() = %v, want %v", got, "12:7")
+	}
+}
@@ -0,0 +1,59 @@
+package tokenizer
+
+import (
+	"io"
+	"jackanalyzer/token"
+)
+
+// Scanner reads tokens one by one from the source.
+//
+// Unlike Tokenize, Scanner does not build the token list,
+// so it keeps only the lookahead tokens in memory.
+type Scanner struct {
+	tz  *Tokenizer
+	buf []token.Token // lookahead tokens read by Peek
+	err error         // error after buf
+}
+
+// NewScanner returns Scanner reading from r configured by opts like New.
+func NewScanner(r io.Reader, opts ...Option) *Scanner {
+	return &Scanner{tz: New(r, opts...)}
+}
+
+// EOFTrivia returns the trivia after the last token like Tokenizer.EOFTrivia.
+// It is complete after Next or Peek returns io.EOF.
+func (s *Scanner) EOFTrivia() []token.Trivia {
+	return s.tz.EOFTrivia()
+}
+
+// Next returns the next token and consumes it.
+// It returns io.EOF after the last token.
+//
+// The returned token does not link to the following token. Token.Next is always nil.
+func (s *Scanner) Next() (token.Token, error) {
+	t, err := s.Peek(0)
+	if err != nil {
+		return t, err
+	}
+	s.buf[0] = token.Token{}
+	s.buf = s.buf[1:]
+	return t, nil
+}
+
+// Peek returns the n-th token ahead without consuming it.
+// Peek(0) returns the token which Next returns.
+// It returns io.EOF if the source ends before the n-th token.
+func (s *Scanner) Peek(n int) (token.Token, error) {
+	for len(s.buf) <= n {
+		if s.err != nil {
+			return token.Token{}, s.err
+		}
+		t, err := s.tz.scan(&token.Token{})
+		if err != nil {
+			s.err = err
+			continue
+		}
+		s.buf = append(s.buf, *t)
+	}
+	return s.buf[n], nil
+}
@@ -0,0 +1,84 @@
+package tokenizer
+
+import (
+	"errors"
+	"io"
+	"jackanalyzer/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner_Next(t *testing.T) {
+	s := NewScanner(strings.NewReader("let x = 1;"))
+	want := []token.Token{
+		{Pos: token.Pos{Offset: 0, Line: 1, Column: 1}, TokenType: token.KEYWORD, Keyword: token.LET},
+		{Pos: token.Pos{Offset: 4, Line: 1, Column: 5}, TokenType: token.IDENTIFIER, Identifier: "x"},
+		{Pos: token.Pos{Offset: 6, Line: 1, Column: 7}, TokenType: token.SYMBOL, Symbol: "="},
+		{Pos: token.Pos{Offset: 8, Line: 1, Column: 9}, TokenType: token.INT_CONST, IntVal: 1},
+		{Pos: token.Pos{Offset: 9, Line: 1, Column: 10}, TokenType: token.SYMBOL, Symbol: ";"},
+	}
+	for i, w := range want {
+		got, err := s.Next()
+		if err != nil {
+			t.Fatalf("Scanner.Next() #%d error = %v", i, err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("Scanner.Next() #%d = %#v, want %#v", i, got, w)
+		}
+	}
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("Scanner.Next() error = %v, want io.EOF", err)
+	}
+	// EOF is sticky
+	if _, err := s.Peek(0); err != io.EOF {
+		t.Errorf("Scanner.Peek() error = %v, want io.EOF", err)
+	}
+}
+
+func TestScanner_Peek(t *testing.T) {
+	s := NewScanner(strings.NewReader("a[b] c"))
+	tests := []struct {
+		name string
+		f    func() (token.Token, error)
+		want string
+	}{
+		{"Peek(1)", func() (token.Token, error) { return s.Peek(1) }, "["},
+		{"Peek(0)", func() (token.Token, error) { return s.Peek(0) }, "a"},
+		{"Peek(3)", func() (token.Token, error) { return s.Peek(3) }, "]"},
+		{"Next", s.Next, "a"},
+		{"Peek(0) after Next", func() (token.Token, error) { return s.Peek(0) }, "["},
+		{"Next", s.Next, "["},
+		{"Peek(2)", func() (token.Token, error) { return s.Peek(2) }, "c"},
+		{"Next", s.Next, "b"},
+	}
+	for _, tt := range tests {
+		got, err := tt.f()
+		if err != nil {
+			t.Fatalf("%s error = %v", tt.name, err)
+		}
+		if v := got.Symbol + got.Identifier; v != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, v, tt.want)
+		}
+	}
+	if _, err := s.Peek(5); err != io.EOF {
+		t.Errorf("Peek(5) error = %v, want io.EOF", err)
+	}
+	if got, err := s.Next(); err != nil || got.Symbol != "]" {
+		t.Errorf("Next() after Peek at EOF = %#v, %v", got, err)
+	}
+}
+
+func TestScanner_error(t *testing.T) {
+	s := NewScanner(strings.NewReader(`x "abc`))
+	if _, err := s.Peek(1); !errors.Is(err, ErrEOFInString) {
+		t.Errorf("Scanner.Peek(1) error = %v, want %v", err, ErrEOFInString)
+	}
+	// the tokens before the error are still available
+	if got, err := s.Next(); err != nil || got.Identifier != "x" {
+		t.Errorf("Scanner.Next() = %#v, %v", got, err)
+	}
+	if _, err := s.Next(); !errors.Is(err, ErrEOFInString) {
+		t.Errorf("Scanner.Next() error = %v, want %v", err, ErrEOFInString)
+	}
+}
@@ -9,18 +9,87 @@ import (
 )
 
 type Tokenizer struct {
-	re *bufio.Reader
+	re   *bufio.Reader
+	pos  token.Pos // position of the next rune
+	prev token.Pos // position before the last ReadRune for unreadRune
+
+	trivia   bool           // keep the trivia and the source text of the tokens
+	leading  []token.Trivia // trivia not yet attached to a token
+	space    []rune         // white space not yet added to leading
+	spacePos token.Pos      // position of space
+	lit      []rune         // source text of the current token
+}
+
+// Option configures Tokenizer.
+type Option func(*Tokenizer)
+
+// WithTrivia keeps the white space and the comments as Token.Leading and Token.Trailing
+// and the source text of the tokens as Token.Lit, so the source can be reproduced.
+// The trivia after the last token is returned by EOFTrivia.
+func WithTrivia() Option {
+	return func(tz *Tokenizer) {
+		tz.trivia = true
+	}
+}
+
+// Error is a tokenize error.
+type Error struct {
+	Pos  token.Pos
+	Kind ErrorKind
+	Msg  string
 }
 
-func New(r io.Reader) *Tokenizer {
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Unwrap returns Kind for errors.Is.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// ErrorKind is a kind of the lexical error.
+type ErrorKind int
+
+const (
+	_                  ErrorKind = iota
+	ErrIntegerOverflow           // integerConstant is greater than MaxIntegerConstant
+	ErrNewlineInString           // stringConstant contains a newline
+	ErrEOFInString               // stringConstant is not closed until EOF
+	ErrEOFInComment              // '/*' comment is not closed until EOF
+	ErrInvalidChar               // character which does not start any token
+)
+
+var errorKinds = map[ErrorKind]string{
+	ErrIntegerOverflow: "integer constant out of range",
+	ErrNewlineInString: "newline in string constant",
+	ErrEOFInString:     "unterminated string constant",
+	ErrEOFInComment:    "unterminated comment",
+	ErrInvalidChar:     "invalid character",
+}
+
+func (k ErrorKind) Error() string {
+	return errorKinds[k]
+}
+
+// MaxIntegerConstant is the maximum value of integerConstant.
+const MaxIntegerConstant = 32767
+
+func New(r io.Reader, opts ...Option) *Tokenizer {
 	re := bufio.NewReader(r)
 	tz := &Tokenizer{
-		re: re,
+		re:  re,
+		pos: token.Pos{Line: 1, Column: 1},
+	}
+	for _, opt := range opts {
+		opt(tz)
 	}
 	return tz
 }
 
-func (tz *Tokenizer) Tokenize() *token.Token {
+// Tokenize returns the head of the token list.
+// The tokens starts from head.Next.
+func (tz *Tokenizer) Tokenize() (*token.Token, error) {
 	head := token.Token{
 		Next: nil,
 	}
@@ -28,143 +97,294 @@ func (tz *Tokenizer) Tokenize() *token.Token {
 
 	// tokenize until EOF comes out
 	for {
-		// call startsWithKeyword
-		kw := tz.startsWithKeyword()
-		if kw != "" {
-			cur = newToken(
-				cur, token.KEYWORD, kw, "", "", 0, "",
-			)
-			continue
+		nt, err := tz.scan(cur)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
+		cur = nt
+	}
+	return &head, nil
+}
 
-		c, _, err := tz.re.ReadRune()
+// EOFTrivia returns the trivia after the Trailing of the last token.
+// It is complete after Tokenize returns or scan returns io.EOF.
+// It is nil without WithTrivia.
+func (tz *Tokenizer) EOFTrivia() []token.Trivia {
+	return tz.leading
+}
+
+// scan reads the next token and links it to cur.Next.
+// With WithTrivia, the trivia before the token is attached to Token.Leading
+// and the trivia on the rest of the line is read into Token.Trailing.
+// It returns io.EOF after the last token.
+func (tz *Tokenizer) scan(cur *token.Token) (*token.Token, error) {
+	nt, err := tz.scanToken(cur)
+	if err != nil {
+		tz.flushSpace()
+		return nil, err
+	}
+	if !tz.trivia {
+		return nt, nil
+	}
+	nt.Lit = string(tz.lit)
+	nt.Leading, tz.leading = tz.leading, nil
+	tz.lit = nil
+	nt.Trailing, err = tz.scanTrailing()
+	if err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
+
+// scanTrailing reads the white space and the comments up to and including the newline.
+// It stops before a token or the comment starting on the next line.
+func (tz *Tokenizer) scanTrailing() ([]token.Trivia, error) {
+	var trailing []token.Trivia
+	for {
+		b, err := tz.re.Peek(1)
 		if err != nil {
-			// TODO return err
+			tz.flushSpace()
+			return append(trailing, tz.takeLeading()...), nil
 		}
-		if err == io.EOF {
-			break
+		switch b[0] {
+		case ' ', '\t', '\r':
+			pos := tz.pos
+			c, _, _ := tz.readRune()
+			tz.addSpace(pos, c)
+		case '\n':
+			pos := tz.pos
+			c, _, _ := tz.readRune()
+			tz.addSpace(pos, c)
+			tz.flushSpace()
+			return append(trailing, tz.takeLeading()...), nil
+		case '/':
+			if b, _ := tz.re.Peek(2); len(b) < 2 || (b[1] != '/' && b[1] != '*') {
+				tz.flushSpace()
+				return append(trailing, tz.takeLeading()...), nil
+			}
+			pos := tz.pos
+			c, _, _ := tz.readRune()
+			_, ct := tz.isComment(c)
+			if err := tz.addComment(pos, ct); err != nil {
+				return nil, err
+			}
+		default:
+			tz.flushSpace()
+			return append(trailing, tz.takeLeading()...), nil
+		}
+	}
+}
+
+// addComment reads the comment after ct and adds it to the trivia.
+func (tz *Tokenizer) addComment(pos token.Pos, ct string) error {
+	text, err := tz.readComment(ct)
+	if err != nil {
+		return newError(pos, err, "")
+	}
+	if !tz.trivia {
+		return nil
+	}
+	tz.flushSpace()
+	kind := token.LINE_COMMENT
+	if ct == token.COMMENT_AST {
+		kind = token.BLOCK_COMMENT
+	}
+	tz.leading = append(tz.leading, token.Trivia{Pos: pos, Kind: kind, Text: text})
+	return nil
+}
+
+// addSpace adds the white space c at pos to the trivia.
+func (tz *Tokenizer) addSpace(pos token.Pos, c rune) {
+	if !tz.trivia {
+		return
+	}
+	if len(tz.space) == 0 {
+		tz.spacePos = pos
+	}
+	tz.space = append(tz.space, c)
+}
+
+// flushSpace adds the pending white space to the trivia as a WHITESPACE.
+func (tz *Tokenizer) flushSpace() {
+	if len(tz.space) == 0 {
+		return
+	}
+	tz.leading = append(tz.leading, token.Trivia{Pos: tz.spacePos, Kind: token.WHITESPACE, Text: string(tz.space)})
+	tz.space = nil
+}
+
+// takeLeading returns the trivia read so far and clears it.
+func (tz *Tokenizer) takeLeading() []token.Trivia {
+	t := tz.leading
+	tz.leading = nil
+	return t
+}
+
+func (tz *Tokenizer) scanToken(cur *token.Token) (*token.Token, error) {
+	for {
+		pos := tz.pos
+		c, _, err := tz.readRune()
+		if err != nil {
+			return nil, err
 		}
 
 		// skip white space
 		if unicode.IsSpace(c) {
+			tz.addSpace(pos, c)
 			continue
 		}
 
 		// isComment
 		if ok, ct := tz.isComment(c); ok {
-			tz.skipComment(ct)
+			if err := tz.addComment(pos, ct); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
+		tz.flushSpace()
+		if tz.trivia {
+			tz.lit = []rune{c}
+		}
+
 		// IsSymbol?
 		// TODO: if unicode.IsPunct() == true
 		if token.IsSymbol(c) {
-			cur = newToken(
-				cur, token.SYMBOL, "", string(c), "", 0, "",
-			)
-			continue
+			return newToken(
+				cur, pos, token.SYMBOL, "", string(c), "", 0, "",
+			), nil
 		}
 
-		// Identifier
+		// Keyword or Identifier
 		if isAlpherUnder(c) {
 			id := tz.startsWithIdentifier(c)
-			cur = newToken(
-				cur, token.IDENTIFIER, "", "", id, 0, "",
-			)
-			continue
+			if kw, ok := lookupKeyword(id); ok {
+				return newToken(
+					cur, pos, token.KEYWORD, kw, "", "", 0, "",
+				), nil
+			}
+			return newToken(
+				cur, pos, token.IDENTIFIER, "", "", id, 0, "",
+			), nil
 		}
 
 		// IntegerConstant
-		if unicode.IsNumber(c) {
-			iv := tz.startsWithIntegerConstant(c)
-			cur = newToken(
-				cur, token.INT_CONST, "", "", "", iv, "",
-			)
-			continue
+		if isDigit(c) {
+			iv, err := tz.startsWithIntegerConstant(c)
+			if err != nil {
+				return nil, newError(pos, err, "integer constant out of range 0.."+strconv.Itoa(MaxIntegerConstant))
+			}
+			return newToken(
+				cur, pos, token.INT_CONST, "", "", "", iv, "",
+			), nil
 		}
 
 		// StringConstant
 		if isDoubleQuotes(c) {
-			sv := tz.startsWithStringConstant()
-			cur = newToken(
-				cur, token.STRING_CONST, "", "", "", 0, sv,
-			)
-			continue
+			sv, err := tz.startsWithStringConstant()
+			if err != nil {
+				return nil, newError(pos, err, "")
+			}
+			return newToken(
+				cur, pos, token.STRING_CONST, "", "", "", 0, sv,
+			), nil
 		}
+
+		return nil, newError(pos, ErrInvalidChar, "invalid character "+strconv.QuoteRune(c))
 	}
-	return &head
 }
 
-func (tz *Tokenizer) startsWithKeyword() token.Keyword {
-	for k, v := range token.Keywords {
-		l := len(k)
-		d, err := tz.re.Peek(l)
-		if err == io.EOF {
-			// TODO return err
-		}
-		if k == string(d) {
-			tz.re.Discard(l)
-			return v
-		}
+// newError returns *Error if err is ErrorKind, otherwise err itself.
+// msg overrides the message of ErrorKind.
+func newError(pos token.Pos, err error, msg string) error {
+	k, ok := err.(ErrorKind)
+	if !ok {
+		return err
+	}
+	if msg == "" {
+		msg = k.Error()
 	}
-	return "" // TODO: Should token.Keyword cotain an empty string??
+	return &Error{Pos: pos, Kind: k, Msg: msg}
+}
+
+// lookupKeyword reports whether the whole identifier lexeme id is a keyword.
+//
+// Keywords are matched against the complete lexeme (maximal munch),
+// so identifiers such as 'dog' or 'letter' are not split into 'do' 'g' or 'let' 'ter'.
+func lookupKeyword(id string) (token.Keyword, bool) {
+	kw, ok := token.Keywords[id]
+	return kw, ok
 }
 
 func (tz *Tokenizer) startsWithIdentifier(r rune) string {
 	id := string(r)
 	for {
-		c, _, err := tz.re.ReadRune()
-		if err == io.EOF {
+		c, _, err := tz.readRune()
+		if err != nil {
 			break
 		}
-		if isAlpherUnder(c) || unicode.IsNumber(c) {
+		if isAlpherUnder(c) || isDigit(c) {
 			id = id + string(c)
 			continue
 		}
-		tz.re.UnreadRune()
+		tz.unreadRune()
 		break
 	}
 	return id
 }
 
-func (tz *Tokenizer) startsWithIntegerConstant(r rune) int {
+// startsWithIntegerConstant reads the decimal digits.
+// It returns ErrIntegerOverflow if the value is greater than MaxIntegerConstant.
+func (tz *Tokenizer) startsWithIntegerConstant(r rune) (int, error) {
 	sr := string(r)
 	for {
-		c, _, err := tz.re.ReadRune()
-		if err == io.EOF {
+		c, _, err := tz.readRune()
+		if err != nil {
 			break
 		}
-		if unicode.IsNumber(c) {
+		if isDigit(c) {
 			sr = sr + string(c)
 			continue
 		}
-		tz.re.UnreadRune()
+		tz.unreadRune()
 		break
 	}
 	iv, err := strconv.Atoi(sr)
-	if err != nil {
-		// TODO return err
+	if err != nil || iv > MaxIntegerConstant {
+		return 0, ErrIntegerOverflow
 	}
-	return iv
+	return iv, nil
 }
 
-func (tz *Tokenizer) startsWithStringConstant() string {
+// startsWithStringConstant reads until the closing double quote.
+// It returns ErrNewlineInString or ErrEOFInString if the closing double quote is missing.
+func (tz *Tokenizer) startsWithStringConstant() (string, error) {
 	var sv string
 	for {
-		c, _, err := tz.re.ReadRune()
+		c, _, err := tz.readRune()
 		if err == io.EOF {
-			break
+			return "", ErrEOFInString
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == '\n' || c == '\r' {
+			return "", ErrNewlineInString
 		}
 		if isDoubleQuotes(c) {
 			break
 		}
 		sv = sv + string(c)
 	}
-	return sv
+	return sv, nil
 }
 
 func newToken(
 	cur *token.Token,
+	pos token.Pos,
 	tt token.TokenType,
 	kw token.Keyword,
 	sb string,
@@ -173,6 +393,7 @@ func newToken(
 	sv string,
 ) *token.Token {
 	nt := token.Token{
+		Pos:        pos,
 		TokenType:  tt,
 		Keyword:    kw,
 		Symbol:     sb,
@@ -184,10 +405,43 @@ func newToken(
 	return &nt
 }
 
+// readRune reads a rune and advances the position.
+func (tz *Tokenizer) readRune() (rune, int, error) {
+	c, size, err := tz.re.ReadRune()
+	if err != nil {
+		return c, size, err
+	}
+	tz.prev = tz.pos
+	if tz.lit != nil {
+		tz.lit = append(tz.lit, c)
+	}
+	tz.pos.Offset += size
+	if c == '\n' {
+		tz.pos.Line++
+		tz.pos.Column = 1
+	} else {
+		tz.pos.Column++
+	}
+	return c, size, nil
+}
+
+// unreadRune unreads the last rune read by readRune.
+func (tz *Tokenizer) unreadRune() {
+	tz.re.UnreadRune()
+	tz.pos = tz.prev
+	if len(tz.lit) > 0 {
+		tz.lit = tz.lit[:len(tz.lit)-1]
+	}
+}
+
 func isAlpherUnder(r rune) bool {
 	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (r == '_')
 }
 
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
 func isDoubleQuotes(r rune) bool {
 	return r == '"'
 }
@@ -196,8 +450,8 @@ func (tz *Tokenizer) isComment(r rune) (bool, string) {
 	if r != '/' {
 		return false, ""
 	}
-	c, _, err := tz.re.ReadRune()
-	if err == io.EOF {
+	c, _, err := tz.readRune()
+	if err != nil {
 		return false, ""
 	}
 	if c == '/' {
@@ -206,32 +460,47 @@ func (tz *Tokenizer) isComment(r rune) (bool, string) {
 	if c == '*' {
 		return true, token.COMMENT_AST
 	}
-	tz.re.UnreadRune()
+	tz.unreadRune()
 	return false, ""
 }
 
-func (tz *Tokenizer) skipComment(ct string) {
-L:
+// readComment reads the comment after '//' or '/*' and returns its whole text.
+// The newline ending COMMENT is not consumed.
+// It returns ErrEOFInComment if '*/' of COMMENT_AST is missing.
+func (tz *Tokenizer) readComment(ct string) (string, error) {
+	text := []rune(ct)
 	for {
-		c, _, err := tz.re.ReadRune()
+		c, _, err := tz.readRune()
 		if err == io.EOF {
-			break
+			if ct == token.COMMENT_AST {
+				return "", ErrEOFInComment
+			}
+			return string(text), nil
+		}
+		if err != nil {
+			return "", err
 		}
 		switch ct {
 		case token.COMMENT:
 			if c == '\n' {
-				break L
+				tz.unreadRune()
+				return string(text), nil
 			}
+			text = append(text, c)
 		case token.COMMENT_AST:
+			text = append(text, c)
 			if c == '*' {
-				c2, _, err := tz.re.ReadRune()
+				c2, _, err := tz.readRune()
 				if err == io.EOF {
-					break L
+					return "", ErrEOFInComment
+				}
+				if err != nil {
+					return "", err
 				}
 				if c2 == '/' {
-					break L
+					return string(append(text, c2)), nil
 				}
-				tz.re.UnreadRune()
+				tz.unreadRune()
 			}
 		}
 	}
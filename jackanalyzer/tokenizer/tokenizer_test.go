@@ -2,9 +2,12 @@ package tokenizer
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"jackanalyzer/token"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -222,14 +225,118 @@ class Main {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tz := New(strings.NewReader(tt.s))
-			if got := tz.Tokenize(); !reflect.DeepEqual(got, tt.want) {
+			got, err := tz.Tokenize()
+			if err != nil {
+				t.Fatalf("JackTokenizer.Tokenize() error = %v", err)
+			}
+			// positions are tested in TestJackTokenizer_Tokenize_pos
+			for v := got; v != nil; v = v.Next {
+				v.Pos = token.Pos{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("JackTokenizer.Tokenize() = %#v, want %#v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestJackTokenizer_startsWithKeyword(t *testing.T) {
+func TestJackTokenizer_Tokenize_pos(t *testing.T) {
+	// the column counts the runes: 'é' and 'ü' are 2 bytes
+	s := "class Main {\n  /* é */ let x = \"ü\";\n\treturn 12;"
+	want := []token.Pos{
+		{Offset: 0, Line: 1, Column: 1},   // class
+		{Offset: 6, Line: 1, Column: 7},   // Main
+		{Offset: 11, Line: 1, Column: 12}, // {
+		{Offset: 24, Line: 2, Column: 11}, // let
+		{Offset: 28, Line: 2, Column: 15}, // x
+		{Offset: 30, Line: 2, Column: 17}, // =
+		{Offset: 32, Line: 2, Column: 19}, // "ü"
+		{Offset: 36, Line: 2, Column: 22}, // ;
+		{Offset: 39, Line: 3, Column: 2},  // return
+		{Offset: 46, Line: 3, Column: 9},  // 12
+		{Offset: 48, Line: 3, Column: 11}, // ;
+	}
+	head, err := New(strings.NewReader(s)).Tokenize()
+	if err != nil {
+		t.Fatalf("JackTokenizer.Tokenize() error = %v", err)
+	}
+	var got []token.Pos
+	for v := head.Next; v != nil; v = v.Next {
+		got = append(got, v.Pos)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JackTokenizer.Tokenize() positions = %v, want %v", got, want)
+	}
+}
+
+func TestJackTokenizer_Tokenize_error(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		wantKind ErrorKind
+		wantErr  string
+	}{
+		{
+			"unterminated string constant",
+			"let s = \"hoge;",
+			ErrEOFInString,
+			"1:9: unterminated string constant",
+		},
+		{
+			"newline in string constant",
+			"let s = \"hoge;\nlet t = \"\";",
+			ErrNewlineInString,
+			"1:9: newline in string constant",
+		},
+		{
+			"unterminated comment",
+			"class Main {\n  /* hoge *",
+			ErrEOFInComment,
+			"2:3: unterminated comment",
+		},
+		{
+			"integer constant overflow",
+			"let i = 32768;",
+			ErrIntegerOverflow,
+			"1:9: integer constant out of range 0..32767",
+		},
+		{
+			"integer constant overflow int",
+			"let i = 99999999999999999999;",
+			ErrIntegerOverflow,
+			"1:9: integer constant out of range 0..32767",
+		},
+		{
+			"invalid character",
+			"let i = $;",
+			ErrInvalidChar,
+			"1:9: invalid character '$'",
+		},
+		{
+			"non-ASCII digit in identifier",
+			"let a١ = 1;",
+			ErrInvalidChar,
+			"1:6: invalid character '١'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(strings.NewReader(tt.s)).Tokenize()
+			if got != nil {
+				t.Errorf("JackTokenizer.Tokenize() = %#v, want nil", got)
+			}
+			var te *Error
+			if !errors.As(err, &te) || te.Error() != tt.wantErr {
+				t.Errorf("JackTokenizer.Tokenize() error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("JackTokenizer.Tokenize() error kind = %v, want %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func Test_lookupKeyword(t *testing.T) {
 	tests := []struct {
 		name string
 		s    string
@@ -343,9 +450,82 @@ func TestJackTokenizer_startsWithKeyword(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tz := New(strings.NewReader(tt.s))
-			if got := tz.startsWithKeyword(); got != tt.want {
-				t.Errorf("JackTokenizer.startsWithKeyword() = %v, want %v", got, tt.want)
+			if got, ok := lookupKeyword(tt.s); got != tt.want || !ok {
+				t.Errorf("lookupKeyword() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lookupKeyword_identifier(t *testing.T) {
+	for _, s := range []string{"dog", "letter", "Class", "iff", ""} {
+		if got, ok := lookupKeyword(s); ok {
+			t.Errorf("lookupKeyword(%q) = %v, want not keyword", s, got)
+		}
+	}
+}
+
+// TestJackTokenizer_Tokenize_keywordPrefix is the regression corpus of
+// identifiers which start with or contain keywords.
+func TestJackTokenizer_Tokenize_keywordPrefix(t *testing.T) {
+	ids := []string{
+		"classify", "classes", "className", "methods", "functional", "constructors",
+		"integer", "int1", "booleans", "character", "chars", "voidable", "variable", "var_",
+		"statics", "staticCount", "fieldName", "letter", "lets", "dog", "doThing", "doSomething",
+		"do2", "_do", "iffy", "if_", "elsewhere", "elseIf", "whileLoop", "returned", "returnValue",
+		"trueValue", "falsehood", "nullable", "thisX", "thisIsIt", "undo", "outlet", "Class",
+	}
+	for _, id := range ids {
+		t.Run(id, func(t *testing.T) {
+			head, err := New(strings.NewReader(id)).Tokenize()
+			if err != nil {
+				t.Fatalf("JackTokenizer.Tokenize() error = %v", err)
+			}
+			want := &token.Token{
+				Pos:        token.Pos{Line: 1, Column: 1},
+				TokenType:  token.IDENTIFIER,
+				Identifier: id,
+			}
+			if !reflect.DeepEqual(head.Next, want) {
+				t.Errorf("JackTokenizer.Tokenize() = %#v, want %#v", head.Next, want)
+			}
+		})
+	}
+}
+
+func TestJackTokenizer_Tokenize_keywordBoundary(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{"symbol after keyword", "if(x){do f();}", []string{"if", "(", "x", ")", "{", "do", "f", "(", ")", ";", "}"}},
+		{"keyword after identifier", "letter let", []string{"letter", "let"}},
+		{"keyword at EOF", "return", []string{"return"}},
+		{"keyword after comment", "/*c*/while//c\nelse", []string{"while", "else"}},
+		{"keyword after digits", "1do", []string{"1", "do"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, err := New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatalf("JackTokenizer.Tokenize() error = %v", err)
+			}
+			var got []string
+			for v := head.Next; v != nil; v = v.Next {
+				switch v.TokenType {
+				case token.KEYWORD:
+					got = append(got, string(v.Keyword))
+				case token.SYMBOL:
+					got = append(got, v.Symbol)
+				case token.IDENTIFIER:
+					got = append(got, v.Identifier)
+				case token.INT_CONST:
+					got = append(got, strconv.Itoa(v.IntVal))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JackTokenizer.Tokenize() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -354,6 +534,7 @@ func TestJackTokenizer_startsWithKeyword(t *testing.T) {
 func TestTokenizer_newToken(t *testing.T) {
 	type args struct {
 		cur *token.Token
+		pos token.Pos
 		tt  token.TokenType
 		kw  token.Keyword
 		sb  string
@@ -370,6 +551,7 @@ func TestTokenizer_newToken(t *testing.T) {
 			"keyword (CLASS)",
 			args{
 				cur: &token.Token{},
+				pos: token.Pos{Offset: 1, Line: 1, Column: 2},
 				tt:  token.KEYWORD,
 				kw:  token.CLASS,
 				// Does not use for KEYWORD
@@ -379,6 +561,7 @@ func TestTokenizer_newToken(t *testing.T) {
 				sv: "",
 			},
 			&token.Token{
+				Pos:       token.Pos{Offset: 1, Line: 1, Column: 2},
 				TokenType: token.KEYWORD,
 				Keyword:   token.CLASS,
 			},
@@ -403,7 +586,7 @@ func TestTokenizer_newToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := newToken(tt.args.cur, tt.args.tt, tt.args.kw, tt.args.sb, tt.args.id, tt.args.iv, tt.args.sv)
+			got := newToken(tt.args.cur, tt.args.pos, tt.args.tt, tt.args.kw, tt.args.sb, tt.args.id, tt.args.iv, tt.args.sv)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Tokenizer.newToken() = %v, want %v", got, tt.want)
 			}
@@ -457,6 +640,14 @@ func TestTokenizer_startsWithIdentifier(t *testing.T) {
 			},
 			"hA",
 		},
+		{
+			"exclude non-ASCII digit",
+			args{
+				r: 'a',
+				s: "1١",
+			},
+			"a1",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -510,17 +701,32 @@ func TestTokenizer_startsWithIntegerConstant(t *testing.T) {
 			},
 			101,
 		},
+		{
+			"test max",
+			args{
+				r: '3',
+				s: "2767;",
+			},
+			32767,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tz := New(strings.NewReader(tt.args.s))
-			if got := tz.startsWithIntegerConstant(tt.args.r); got != tt.want {
+			if got, err := tz.startsWithIntegerConstant(tt.args.r); got != tt.want || err != nil {
 				t.Errorf("Tokenizer.startsWithIntegerConstant() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestTokenizer_startsWithIntegerConstant_overflow(t *testing.T) {
+	tz := New(strings.NewReader("2768"))
+	if _, err := tz.startsWithIntegerConstant('3'); err != ErrIntegerOverflow {
+		t.Errorf("Tokenizer.startsWithIntegerConstant() error = %v, want %v", err, ErrIntegerOverflow)
+	}
+}
+
 func Test_isAlpherUnder(t *testing.T) {
 	tests := []struct {
 		name string
@@ -564,22 +770,40 @@ func Test_isAlpherUnder(t *testing.T) {
 
 func TestTokenizer_startsWithStringConstant(t *testing.T) {
 	tests := []struct {
-		name string
-		s    string
-		want string
+		name    string
+		s       string
+		want    string
+		wantErr error
 	}{
 		{
 			"test",
 			`test"`, // Suppose you are getting the first double quate with Tokenize().
 			"test",
+			nil,
+		},
+		{
+			"unterminated",
+			`test`,
+			"",
+			ErrEOFInString,
+		},
+		{
+			"newline",
+			"test\n\"",
+			"",
+			ErrNewlineInString,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tz := New(strings.NewReader(tt.s))
-			if got := tz.startsWithStringConstant(); got != tt.want {
+			got, err := tz.startsWithStringConstant()
+			if got != tt.want {
 				t.Errorf("Tokenizer.startsWithStringConstant() = %s, want %s", got, tt.want)
 			}
+			if err != tt.wantErr {
+				t.Errorf("Tokenizer.startsWithStringConstant() error = %v, want %v", err, tt.wantErr)
+			}
 		})
 	}
 }
@@ -676,42 +900,182 @@ func TestTokenizer_isComment(t *testing.T) {
 	}
 }
 
-func TestTokenizer_skipComment(t *testing.T) {
+func TestTokenizer_readComment(t *testing.T) {
 	type args struct {
 		ct string
 		s  string
 	}
 	tests := []struct {
-		name string
-		args args
-		want string
+		name     string
+		args     args
+		wantText string
+		want     string
+		wantErr  error
 	}{
 		{
 			"comment",
 			args{
 				ct: token.COMMENT,
-				s: `// comment
+				s: ` comment
 abc`,
 			},
-			"abc",
+			"// comment",
+			"",
+			nil,
+		},
+		{
+			"comment crlf",
+			args{
+				ct: token.COMMENT,
+				s:  " comment\r\nabc",
+			},
+			"// comment\r",
+			"",
+			nil,
+		},
+		{
+			"comment at EOF",
+			args{
+				ct: token.COMMENT,
+				s:  " comment",
+			},
+			"// comment",
+			"",
+			nil,
 		},
 		{
 			"comment asterisk",
 			args{
 				ct: token.COMMENT_AST,
-				s:  `/* comment cocococo */ abc`,
+				s:  ` comment * cocococo */ abc`,
 			},
+			"/* comment * cocococo */",
 			" abc",
+			nil,
+		},
+		{
+			"unterminated comment asterisk",
+			args{
+				ct: token.COMMENT_AST,
+				s:  ` comment cocococo *`,
+			},
+			"",
+			"",
+			ErrEOFInComment,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tz := New(strings.NewReader(tt.args.s))
-			tz.skipComment(tt.args.ct)
+			text, err := tz.readComment(tt.args.ct)
+			if err != tt.wantErr {
+				t.Errorf("Tokenizer.readComment() error = %v, want %v", err, tt.wantErr)
+			}
+			if text != tt.wantText {
+				t.Errorf("Tokenizer.readComment() = %q, want %q", text, tt.wantText)
+			}
 			l, _, _ := tz.re.ReadLine()
 			if string(l) != tt.want {
-				t.Errorf("Tokenizer.skipComment() tz.re.ReadLine = %s, want %s", l, tt.want)
+				t.Errorf("Tokenizer.readComment() tz.re.ReadLine = %s, want %s", l, tt.want)
 			}
 		})
 	}
 }
+
+func TestTokenizer_trivia(t *testing.T) {
+	s := `// head
+class /* a */ Main { // b
+
+  /** doc
+   */
+}
+// tail`
+	tz := New(strings.NewReader(s), WithTrivia())
+	head, err := tz.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for tk := head.Next; tk != nil; tk = tk.Next {
+		got = append(got, fmt.Sprintf("%v %q leading=%s trailing=%s", tk.Pos, tk.Lit, trivia(tk.Leading), trivia(tk.Trailing)))
+	}
+	got = append(got, "EOF "+trivia(tz.EOFTrivia()))
+	want := []string{
+		`2:1 "class" leading=[1:1 LINE_COMMENT "// head" 1:8 WHITESPACE "\n"] trailing=[2:6 WHITESPACE " " 2:7 BLOCK_COMMENT "/* a */" 2:14 WHITESPACE " "]`,
+		`2:15 "Main" leading=[] trailing=[2:19 WHITESPACE " "]`,
+		`2:20 "{" leading=[] trailing=[2:21 WHITESPACE " " 2:22 LINE_COMMENT "// b" 2:26 WHITESPACE "\n"]`,
+		`6:1 "}" leading=[3:1 WHITESPACE "\n  " 4:3 BLOCK_COMMENT "/** doc\n   */" 5:6 WHITESPACE "\n"] trailing=[6:2 WHITESPACE "\n"]`,
+		`EOF [7:1 LINE_COMMENT "// tail"]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("trivia = \n%v\nwant \n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTokenizer_trivia_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"spaces only", " \n\t\n"},
+		{"crlf", "class Main {\r\n  // c\r\n  field int x; /* d */\r\n}\r\n"},
+		{"tokens", `class Main { function void main() { let s = "a b"; let i = 007; return; } }`},
+		{"slash", "let x = a/b; // c\n/* d */let y = -x;"},
+		{"no newline at EOF", "} // c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tz := New(strings.NewReader(tt.s), WithTrivia())
+			head, err := tz.Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			for tk := head.Next; tk != nil; tk = tk.Next {
+				for _, v := range tk.Leading {
+					b.WriteString(v.Text)
+				}
+				b.WriteString(tk.Lit)
+				for _, v := range tk.Trailing {
+					b.WriteString(v.Text)
+				}
+			}
+			for _, v := range tz.EOFTrivia() {
+				b.WriteString(v.Text)
+			}
+			if b.String() != tt.s {
+				t.Errorf("round trip = %q, want %q", b.String(), tt.s)
+			}
+		})
+	}
+}
+
+func TestTokenizer_noTrivia(t *testing.T) {
+	tz := New(strings.NewReader("// c\nclass /* a */ Main {}\n"))
+	head, err := tz.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tk := head.Next; tk != nil; tk = tk.Next {
+		if tk.Lit != "" || tk.Leading != nil || tk.Trailing != nil {
+			t.Errorf("token %v has trivia %q %v %v", tk.Pos, tk.Lit, tk.Leading, tk.Trailing)
+		}
+	}
+	if got := tz.EOFTrivia(); got != nil {
+		t.Errorf("EOFTrivia() = %v, want nil", got)
+	}
+}
+
+func trivia(ts []token.Trivia) string {
+	kinds := map[token.TriviaKind]string{
+		token.WHITESPACE:    "WHITESPACE",
+		token.LINE_COMMENT:  "LINE_COMMENT",
+		token.BLOCK_COMMENT: "BLOCK_COMMENT",
+	}
+	var s []string
+	for _, v := range ts {
+		s = append(s, fmt.Sprintf("%v %s %q", v.Pos, kinds[v.Kind], v.Text))
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
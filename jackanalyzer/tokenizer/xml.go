@@ -0,0 +1,53 @@
+package tokenizer
+
+import (
+	"bufio"
+	"io"
+	"jackanalyzer/token"
+	"strconv"
+	"strings"
+)
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+// WriteXML writes the tokens following head in the XxxT.xml format of nand2tetris.
+// The lines end with LF while the compare files end them with CRLF.
+//
+//  <tokens>
+//  <keyword> class </keyword>
+//  <identifier> Main </identifier>
+//  <symbol> { </symbol>
+//  ...
+//  </tokens>
+func WriteXML(w io.Writer, head *token.Token) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<tokens>\n")
+	for t := head.Next; t != nil; t = t.Next {
+		l, c := xmlElement(t)
+		bw.WriteString("<" + l + "> " + xmlEscaper.Replace(c) + " </" + l + ">\n")
+	}
+	bw.WriteString("</tokens>\n")
+	return bw.Flush()
+}
+
+// xmlElement returns the label and the contents of the token.
+func xmlElement(t *token.Token) (string, string) {
+	switch t.TokenType {
+	case token.KEYWORD:
+		return "keyword", string(t.Keyword)
+	case token.SYMBOL:
+		return "symbol", t.Symbol
+	case token.IDENTIFIER:
+		return "identifier", t.Identifier
+	case token.INT_CONST:
+		return "integerConstant", strconv.Itoa(t.IntVal)
+	case token.STRING_CONST:
+		return "stringConstant", t.StringVal
+	}
+	return "", ""
+}
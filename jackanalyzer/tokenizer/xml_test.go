@@ -0,0 +1,70 @@
+package tokenizer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteXML(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			"empty",
+			"// comment only",
+			`<tokens>
+</tokens>
+`,
+		},
+		{
+			"all token types",
+			`let x = "a & b"; if (x < 10) {}`,
+			`<tokens>
+<keyword> let </keyword>
+<identifier> x </identifier>
+<symbol> = </symbol>
+<stringConstant> a &amp; b </stringConstant>
+<symbol> ; </symbol>
+<keyword> if </keyword>
+<symbol> ( </symbol>
+<identifier> x </identifier>
+<symbol> &lt; </symbol>
+<integerConstant> 10 </integerConstant>
+<symbol> ) </symbol>
+<symbol> { </symbol>
+<symbol> } </symbol>
+</tokens>
+`,
+		},
+		{
+			"escape",
+			`a > b & c`,
+			`<tokens>
+<identifier> a </identifier>
+<symbol> &gt; </symbol>
+<identifier> b </identifier>
+<symbol> &amp; </symbol>
+<identifier> c </identifier>
+</tokens>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, err := New(strings.NewReader(tt.s)).Tokenize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := WriteXML(&b, head); err != nil {
+				t.Fatalf("WriteXML() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("WriteXML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package vmwriter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Segment is the memory segment of the VM.
+type Segment string
+
+const (
+	CONST   Segment = "constant"
+	ARG     Segment = "argument"
+	LOCAL   Segment = "local"
+	STATIC  Segment = "static"
+	THIS    Segment = "this"
+	THAT    Segment = "that"
+	POINTER Segment = "pointer"
+	TEMP    Segment = "temp"
+)
+
+// Command is the arithmetic-logical command of the VM.
+type Command string
+
+const (
+	ADD Command = "add"
+	SUB Command = "sub"
+	NEG Command = "neg"
+	EQ  Command = "eq"
+	GT  Command = "gt"
+	LT  Command = "lt"
+	AND Command = "and"
+	OR  Command = "or"
+	NOT Command = "not"
+)
+
+// VMWriter writes the VM commands.
+//
+// The write error is kept until Flush like bufio.Writer.
+type VMWriter struct {
+	w *bufio.Writer
+}
+
+// New returns VMWriter.
+func New(w io.Writer) *VMWriter {
+	vw := &VMWriter{
+		w: bufio.NewWriter(w),
+	}
+	return vw
+}
+
+// WritePush writes 'push segment index'.
+func (vw *VMWriter) WritePush(seg Segment, index int) {
+	fmt.Fprintf(vw.w, "push %s %d\n", seg, index)
+}
+
+// WritePop writes 'pop segment index'.
+func (vw *VMWriter) WritePop(seg Segment, index int) {
+	fmt.Fprintf(vw.w, "pop %s %d\n", seg, index)
+}
+
+// WriteArithmetic writes the arithmetic-logical command.
+func (vw *VMWriter) WriteArithmetic(cmd Command) {
+	fmt.Fprintf(vw.w, "%s\n", cmd)
+}
+
+// WriteLabel writes 'label label'.
+func (vw *VMWriter) WriteLabel(label string) {
+	fmt.Fprintf(vw.w, "label %s\n", label)
+}
+
+// WriteGoto writes 'goto label'.
+func (vw *VMWriter) WriteGoto(label string) {
+	fmt.Fprintf(vw.w, "goto %s\n", label)
+}
+
+// WriteIf writes 'if-goto label'.
+func (vw *VMWriter) WriteIf(label string) {
+	fmt.Fprintf(vw.w, "if-goto %s\n", label)
+}
+
+// WriteCall writes 'call name nArgs'.
+func (vw *VMWriter) WriteCall(name string, nArgs int) {
+	fmt.Fprintf(vw.w, "call %s %d\n", name, nArgs)
+}
+
+// WriteFunction writes 'function name nLocals'.
+func (vw *VMWriter) WriteFunction(name string, nLocals int) {
+	fmt.Fprintf(vw.w, "function %s %d\n", name, nLocals)
+}
+
+// WriteReturn writes 'return'.
+func (vw *VMWriter) WriteReturn() {
+	fmt.Fprintf(vw.w, "return\n")
+}
+
+// Flush writes the buffered commands and returns the first write error.
+func (vw *VMWriter) Flush() error {
+	return vw.w.Flush()
+}
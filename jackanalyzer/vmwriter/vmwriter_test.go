@@ -0,0 +1,54 @@
+package vmwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVMWriter(t *testing.T) {
+	var b bytes.Buffer
+	vw := New(&b)
+	vw.WriteFunction("Main.main", 1)
+	vw.WritePush(CONST, 8)
+	vw.WritePop(LOCAL, 0)
+	vw.WriteLabel("WHILE_EXP0")
+	vw.WritePush(LOCAL, 0)
+	vw.WriteArithmetic(NOT)
+	vw.WriteIf("WHILE_END0")
+	vw.WriteGoto("WHILE_EXP0")
+	vw.WriteCall("Math.multiply", 2)
+	vw.WriteReturn()
+	if err := vw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `function Main.main 1
+push constant 8
+pop local 0
+label WHILE_EXP0
+push local 0
+not
+if-goto WHILE_END0
+goto WHILE_EXP0
+call Math.multiply 2
+return
+`
+	if got := b.String(); got != want {
+		t.Errorf("VMWriter got = %v, want = %v", got, want)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestVMWriter_Flush_error(t *testing.T) {
+	vw := New(errWriter{})
+	vw.WriteReturn()
+	if err := vw.Flush(); err == nil || err.Error() != "write error" {
+		t.Errorf("Flush() error = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package workspace
+
+import (
+	"jackanalyzer/element"
+	"jackanalyzer/symboltable"
+)
+
+// calledClasses returns the classes whose subroutines are called in f.Class,
+// resolved like program.CheckCalls: the class of the unqualified calls,
+// the type of varName and className.
+func calledClasses(f *File) map[string]bool {
+	c := &caller{calls: map[string]bool{}, cn: string(f.Class.Cn)}
+	for _, sd := range f.Class.Sds {
+		c.st = f.Scope(sd)
+		c.statements(sd.Sb.Stmts)
+	}
+	return c.calls
+}
+
+type caller struct {
+	calls map[string]bool
+	cn    string
+	st    *symboltable.SymbolTable
+}
+
+func (c *caller) statements(stmts []element.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *element.LetStatement:
+			if s.Lexp != nil {
+				c.expression(s.Lexp)
+			}
+			c.expression(&s.Rexp)
+		case *element.IfStatement:
+			c.expression(&s.LExp)
+			c.statements(s.Stmts)
+			c.statements(s.EStmts)
+		case *element.WhileStatement:
+			c.expression(&s.Exp)
+			c.statements(s.Stmts)
+		case *element.DoStatement:
+			c.subroutineCall(s.Sub)
+		case *element.ReturnStatement:
+			if s.Exp != nil {
+				c.expression(s.Exp)
+			}
+		}
+	}
+}
+
+func (c *caller) expression(exp *element.Expression) {
+	c.term(exp.Term)
+	for _, v := range exp.Next {
+		c.term(v.Term)
+	}
+}
+
+func (c *caller) term(term element.Term) {
+	switch t := term.(type) {
+	case *element.CallIndex:
+		c.expression(&t.Exp)
+	case *element.SubroutineCall:
+		c.subroutineCall(t)
+	case *element.Args:
+		c.expression(&t.Exp)
+	case *element.UopTerm:
+		c.term(t.Term)
+	}
+}
+
+func (c *caller) subroutineCall(sbc *element.SubroutineCall) {
+	for i := range sbc.ExpL {
+		c.expression(&sbc.ExpL[i])
+	}
+	cn := c.cn
+	if sbc.Dot != "" {
+		cn = string(sbc.Name)
+		if s, ok := c.st.Lookup(cn); ok {
+			cn = s.Type
+		}
+	}
+	c.calls[cn] = true
+}
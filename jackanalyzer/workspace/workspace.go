@@ -0,0 +1,271 @@
+package workspace
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"jackanalyzer/check"
+	"jackanalyzer/cmplengn"
+	"jackanalyzer/element"
+	"jackanalyzer/flow"
+	"jackanalyzer/program"
+	"jackanalyzer/symboltable"
+	"jackanalyzer/token"
+	"jackanalyzer/tokenizer"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// File is the cached analysis of a .jack file.
+// It is replaced, not modified, when the contents change.
+type File struct {
+	Path    string
+	Hash    [sha256.Size]byte        // SHA-256 of the contents
+	Tokens  []*token.Token           // with the trivia, nil on the tokenize error
+	Class   *element.Class           // partial on the syntax errors, nil on the tokenize error
+	Err     error                    // *tokenizer.Error or cmplengn.ErrorList
+	Symbols *symboltable.SymbolTable // the class scope of Class
+
+	scopes map[*element.SubroutineDec]*symboltable.SymbolTable
+	calls  map[string]bool // the classes whose subroutines Class calls
+	diags  check.Diagnostics
+	stale  bool // diags must be computed again
+}
+
+// Scope returns the symbol table of sd of Class.
+func (f *File) Scope(sd *element.SubroutineDec) *symboltable.SymbolTable {
+	st, ok := f.scopes[sd]
+	if !ok {
+		st = symboltable.NewClass(f.Class)
+		st.StartSubroutineDec(sd)
+		f.scopes[sd] = st
+	}
+	return st
+}
+
+// Workspace is the analysis cache of the .jack files of a program.
+//
+// Update parses only the file whose contents changed.
+// The diagnostics are computed again only for the changed file
+// and the files calling into a class whose signatures changed.
+type Workspace struct {
+	files map[string]*File
+	os    []*element.Class // the Jack OS stubs
+	index program.Index
+
+	parses   int // the number of the files parsed, for the tests
+	analyses int // the number of the files analyzed, for the tests
+}
+
+// New returns the empty Workspace with the Jack OS classes.
+func New() *Workspace {
+	w := &Workspace{
+		files: map[string]*File{},
+		os:    program.OSClasses(),
+		index: program.Index{},
+	}
+	for _, cl := range w.os {
+		w.index.Add(cl)
+	}
+	return w
+}
+
+// Load adds every .jack file directly under dir.
+// The diagnostics are computed on demand.
+func (w *Workspace) Load(dir string) error {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".jack" {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		w.set(path, src)
+	}
+	return nil
+}
+
+// Update sets the contents of the file of path and returns its diagnostics:
+// the tokenize or syntax errors, or the errors of check.Check and program.CheckCalls
+// and the warnings of flow.Check and flow.CheckAssignments.
+// The unchanged contents are not analyzed again.
+func (w *Workspace) Update(path string, contents []byte) check.Diagnostics {
+	w.set(path, contents)
+	return w.Diagnostics(path)
+}
+
+// Remove removes the file of path.
+func (w *Workspace) Remove(path string) {
+	f, ok := w.files[path]
+	if !ok {
+		return
+	}
+	delete(w.files, path)
+	if f.Class != nil {
+		w.reindex(string(f.Class.Cn))
+	}
+}
+
+// File returns the file of path.
+func (w *Workspace) File(path string) (*File, bool) {
+	f, ok := w.files[path]
+	return f, ok
+}
+
+// Diagnostics returns the diagnostics of the file of path like Update.
+func (w *Workspace) Diagnostics(path string) check.Diagnostics {
+	f, ok := w.files[path]
+	if !ok {
+		return nil
+	}
+	if f.stale {
+		f.diags = w.analyze(f)
+		f.stale = false
+	}
+	return f.diags
+}
+
+// Stale reports whether the diagnostics of the file of path must be computed again
+// since a class it calls into changed.
+func (w *Workspace) Stale(path string) bool {
+	f, ok := w.files[path]
+	return ok && f.stale
+}
+
+// Program returns the files as Program in the order of the paths.
+// The classes are shared with Workspace and must not be modified.
+func (w *Workspace) Program() *program.Program {
+	p := &program.Program{Index: w.index}
+	for _, path := range w.paths() {
+		f := w.files[path]
+		p.Files = append(p.Files, &program.File{Path: f.Path, Class: f.Class, Err: f.Err})
+	}
+	for _, cl := range w.os {
+		if w.declaring(string(cl.Cn)) == nil {
+			p.OS = append(p.OS, cl)
+		}
+	}
+	return p
+}
+
+// set parses contents unless the file has the same contents.
+func (w *Workspace) set(path string, contents []byte) {
+	old, ok := w.files[path]
+	hash := sha256.Sum256(contents)
+	if ok && old.Hash == hash {
+		return
+	}
+	f := w.parse(path, contents, hash)
+	w.files[path] = f
+	if ok && old.Class != nil {
+		w.reindex(string(old.Class.Cn))
+	}
+	if f.Class != nil {
+		w.reindex(string(f.Class.Cn))
+	}
+}
+
+func (w *Workspace) parse(path string, contents []byte, hash [sha256.Size]byte) *File {
+	w.parses++
+	f := &File{
+		Path:   path,
+		Hash:   hash,
+		scopes: map[*element.SubroutineDec]*symboltable.SymbolTable{},
+		stale:  true,
+	}
+	head, err := tokenizer.New(bytes.NewReader(contents), tokenizer.WithTrivia()).Tokenize()
+	if err != nil {
+		f.Err = err
+		return f
+	}
+	for t := head.Next; t != nil; t = t.Next {
+		f.Tokens = append(f.Tokens, t)
+	}
+	f.Class, f.Err = cmplengn.Parse(head)
+	if f.Class == nil {
+		// no class header: only the syntax error is reported
+		return f
+	}
+	f.Symbols = symboltable.NewClass(f.Class)
+	f.calls = calledClasses(f)
+	return f
+}
+
+// reindex indexes the class of name again: the first file declaring it
+// in the order of the paths, or the Jack OS stub.
+// The files calling into the class are analyzed again if its signatures changed.
+func (w *Workspace) reindex(name string) {
+	before := w.index[name]
+	delete(w.index, name)
+	if f := w.declaring(name); f != nil {
+		w.index.Add(f.Class)
+	} else {
+		for _, cl := range w.os {
+			if string(cl.Cn) == name {
+				w.index.Add(cl)
+			}
+		}
+	}
+	if reflect.DeepEqual(before, w.index[name]) {
+		return
+	}
+	for _, f := range w.files {
+		if f.calls[name] {
+			f.stale = true
+		}
+	}
+}
+
+// declaring returns the first file declaring the class of name.
+func (w *Workspace) declaring(name string) *File {
+	for _, path := range w.paths() {
+		if f := w.files[path]; f.Class != nil && string(f.Class.Cn) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (w *Workspace) paths() []string {
+	paths := make([]string, 0, len(w.files))
+	for path := range w.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (w *Workspace) analyze(f *File) check.Diagnostics {
+	w.analyses++
+	var diags check.Diagnostics
+	var te *tokenizer.Error
+	var el cmplengn.ErrorList
+	switch {
+	case errors.As(f.Err, &te):
+		diags = append(diags, check.Diagnostic{Pos: te.Pos, Severity: check.ERROR, Msg: te.Msg})
+	case errors.As(f.Err, &el):
+		for _, e := range el {
+			diags = append(diags, check.Diagnostic{Pos: e.Pos, Severity: check.ERROR, Msg: e.Msg})
+		}
+	}
+	if f.Class == nil || f.Err != nil {
+		return diags
+	}
+
+	diags = append(diags, check.Check(f.Path, f.Class)...)
+	diags = append(diags, program.CheckCalls(f.Class, w.index)...)
+	diags = append(diags, flow.Check(f.Class)...)
+	diags = append(diags, flow.CheckAssignments(f.Class)...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	})
+	return diags
+}
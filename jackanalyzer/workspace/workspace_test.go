@@ -0,0 +1,197 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	ballSrc = `class Ball {
+    field int x;
+    constructor Ball new() { let x = 0; return this; }
+    method void move() { let x = x + 1; return; }
+}
+`
+	mainSrc = `class Main {
+    function void main() {
+        var Ball b;
+        let b = Ball.new();
+        do b.move();
+        do Output.printInt(Math.abs(-1));
+        return;
+    }
+}
+`
+	otherSrc = `class Other {
+    function int one() { return 1; }
+}
+`
+)
+
+func TestWorkspace_Update(t *testing.T) {
+	w := New()
+	paths := []string{"Ball.jack", "Main.jack", "Other.jack"}
+	steps := []struct {
+		name         string
+		path         string
+		src          string // removes the file if empty
+		wantParses   int    // parsed by the step
+		wantAnalyses int    // analyzed by the step and Diagnostics of every file
+		want         []string
+	}{
+		{"add Ball", "Ball.jack", ballSrc, 1, 1, nil},
+		{"add Main", "Main.jack", mainSrc, 1, 1, nil},
+		{"add Other", "Other.jack", otherSrc, 1, 1, nil},
+		{"same contents", "Main.jack", mainSrc, 0, 0, nil},
+		{
+			"body of Ball",
+			"Ball.jack", strings.Replace(ballSrc, "x + 1", "x + 2", 1),
+			1, 1, nil,
+		},
+		{
+			"signature of Ball",
+			"Ball.jack", strings.Replace(ballSrc, "move()", "move(int dx)", 1),
+			1, 2, []string{"Main.jack:5:12: 'Ball.move' takes 1 arguments, got 0"},
+		},
+		{
+			"syntax error of Other",
+			"Other.jack", "class Other {",
+			1, 1, []string{"Main.jack:5:12: 'Ball.move' takes 1 arguments, got 0", "Other.jack:1:13: expected '}', got EOF"},
+		},
+		{
+			"remove Ball",
+			"Ball.jack", "",
+			0, 1, []string{"Main.jack:4:17: undefined class 'Ball'", "Main.jack:5:12: undefined class 'Ball'", "Other.jack:1:13: expected '}', got EOF"},
+		},
+		{
+			"override Math",
+			"Math.jack", "class Math {\n    function int sqrt(int x) { return x; }\n}\n",
+			1, 2, []string{"Main.jack:4:17: undefined class 'Ball'", "Main.jack:5:12: undefined class 'Ball'", "Main.jack:6:28: undefined subroutine 'abs' in class Math", "Other.jack:1:13: expected '}', got EOF"},
+		},
+		{
+			"remove Math",
+			"Math.jack", "",
+			0, 1, []string{"Main.jack:4:17: undefined class 'Ball'", "Main.jack:5:12: undefined class 'Ball'", "Other.jack:1:13: expected '}', got EOF"},
+		},
+	}
+	for _, st := range steps {
+		parses, analyses := w.parses, w.analyses
+		if st.src == "" {
+			w.Remove(st.path)
+		} else {
+			w.Update(st.path, []byte(st.src))
+		}
+		var got []string
+		for _, path := range paths {
+			for _, d := range w.Diagnostics(path) {
+				got = append(got, path+":"+d.String())
+			}
+		}
+		if !reflect.DeepEqual(got, st.want) {
+			t.Errorf("%s: diagnostics = %q, want %q", st.name, got, st.want)
+		}
+		if w.parses-parses != st.wantParses {
+			t.Errorf("%s: parsed %d files, want %d", st.name, w.parses-parses, st.wantParses)
+		}
+		if w.analyses-analyses != st.wantAnalyses {
+			t.Errorf("%s: analyzed %d files, want %d", st.name, w.analyses-analyses, st.wantAnalyses)
+		}
+	}
+}
+
+func TestWorkspace_Load(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"Main.jack":   mainSrc,
+		"Ball.jack":   ballSrc,
+		"Output.jack": "class Output {\n    function void printInt(int i) { return; }\n}\n",
+		"notes.txt":   "not a class",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := New()
+	if err := w.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if w.analyses != 0 {
+		t.Errorf("Load() analyzed %d files, want 0", w.analyses)
+	}
+	if d := w.Diagnostics(filepath.Join(dir, "Main.jack")); len(d) != 0 {
+		t.Errorf("Diagnostics() = %v, want none", d)
+	}
+
+	p := w.Program()
+	var files, os []string
+	for _, f := range p.Files {
+		files = append(files, filepath.Base(f.Path))
+	}
+	for _, cl := range p.OS {
+		os = append(os, string(cl.Cn))
+	}
+	if want := []string{"Ball.jack", "Main.jack", "Output.jack"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Program().Files = %v, want %v", files, want)
+	}
+	if want := []string{"Math", "String", "Array", "Screen", "Keyboard", "Memory", "Sys"}; !reflect.DeepEqual(os, want) {
+		t.Errorf("Program().OS = %v, want %v", os, want)
+	}
+	if _, ok := p.Index.Lookup("Output", "println"); ok {
+		t.Error("Program().Index has the stub of the overridden Output")
+	}
+
+	f, ok := w.File(filepath.Join(dir, "Main.jack"))
+	if !ok {
+		t.Fatal("File() not found")
+	}
+	if s, ok := f.Scope(f.Class.Sds[0]).Lookup("b"); !ok || s.Type != "Ball" {
+		t.Errorf("Scope().Lookup(b) = %v, %v", s, ok)
+	}
+	if f.Tokens[0].Lit != "class" {
+		t.Errorf("Tokens[0].Lit = %q, want class", f.Tokens[0].Lit)
+	}
+
+	if err := New().Load(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Load() of a missing directory error = nil")
+	}
+}
+
+func TestWorkspace_noClass(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"empty", "", []string{"0:0: expected [class], got EOF"}},
+		{"keyword prefix", "cla", []string{"1:1: expected [class], got identifier 'cla'"}},
+		{"no class name", "class {", []string{"1:7: expected identifier, got symbol '{'"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "Main.jack")
+			if err := ioutil.WriteFile(path, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			w := New()
+			if err := w.Load(dir); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range w.Update(path, []byte(tt.src+" ")) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Update() = %q, want %q", got, tt.want)
+			}
+			f, _ := w.File(path)
+			if f.Class != nil || f.Symbols != nil || len(f.calls) != 0 {
+				t.Errorf("File() = %+v, want no class", f)
+			}
+			w.Program()
+		})
+	}
+}